@@ -0,0 +1,62 @@
+package stdvmix
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sdcontext "github.com/FlowingSPDG/streamdeck/context"
+)
+
+// volumeHold how long a volume sent from a key/dial wins over the snapshot.
+// Dials rotate faster than vMix reports the new volume.
+const volumeHold = time.Second
+
+// pendingVolume volume sent by a key/dial that vMix may not have reported yet
+type pendingVolume struct {
+	volume float64
+	at     time.Time
+}
+
+// currentVolume volume to step from and to display
+func (s *StdVmix) currentVolume(ctxStr string, reported float64) float64 {
+	if v, ok := s.volumes.Load(ctxStr); ok {
+		if p := v.(pendingVolume); time.Since(p.at) < volumeHold {
+			return p.volume
+		}
+	}
+	return reported
+}
+
+// stepVolume add pi.Step * steps to the current volume
+func (s *StdVmix) stepVolume(ctx context.Context, pi AudioPI, steps int) error {
+	ctxStr := sdcontext.Context(ctx)
+	st, err := s.store.State(pi.Target())
+	if err != nil {
+		return err
+	}
+	volume, _, err := pi.UpdateLevel(st)
+	if err != nil {
+		return err
+	}
+	v := s.currentVolume(ctxStr, volume) + float64(pi.Step*steps)
+	if v < 0 {
+		v = 0
+	} else if v > 100 {
+		v = 100
+	}
+	if err := pi.SetVolume(ctx, s.store, v); err != nil {
+		return fmt.Errorf("Failed to set volume:%w", err)
+	}
+	s.volumes.Store(ctxStr, pendingVolume{volume: v, at: time.Now()})
+	s.store.Refresh(pi.Target())
+	return nil
+}
+
+// levelTitle volume shown on the key/touch strip
+func levelTitle(volume float64, muted bool) string {
+	if muted {
+		return "MUTE"
+	}
+	return fmt.Sprintf("%.0f", volume)
+}
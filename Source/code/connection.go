@@ -0,0 +1,71 @@
+package stdvmix
+
+import (
+	"net"
+	"strconv"
+)
+
+// GlobalSettings plugin-wide settings stored with setGlobalSettings
+type GlobalSettings struct {
+	Connections []Connection `json:"connections"`
+	Tally       TallyColors  `json:"tally"` // default tally colors of every key
+}
+
+// Connection named vMix connection profile.
+// Actions reference it by ID so moving a show to another machine is a single edit.
+type Connection struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Host     string `json:"host"`
+	Port     int    `json:"port,string"`     // HTTP API port. Not used to connect, only to match legacy Host/Port settings on migration
+	TCPPort  int    `json:"tcp_port,string"` // TCP API port
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+// vmixTarget connection settings of an action.
+// Connection profile ID takes priority, Host/Port is used for actions created before profiles existed.
+type vmixTarget struct {
+	Connection string
+	Host       string
+	Port       int
+}
+
+// endpoint resolved vMix TCP API destination
+type endpoint struct {
+	Host     string
+	TCPPort  int
+	User     string
+	Password string
+}
+
+// Addr host:port of TCP API. Used as the key to share state between actions.
+func (e endpoint) Addr() string {
+	return net.JoinHostPort(e.Host, strconv.Itoa(e.TCPPort))
+}
+
+// resolveTarget resolve target with connection profiles. false if nothing to connect to.
+func resolveTarget(conns []Connection, t vmixTarget) (endpoint, bool) {
+	if t.Connection != "" {
+		for _, c := range conns {
+			if c.ID != t.Connection {
+				continue
+			}
+			ep := endpoint{
+				Host:     c.Host,
+				TCPPort:  c.TCPPort,
+				User:     c.User,
+				Password: c.Password,
+			}
+			if ep.TCPPort == 0 {
+				ep.TCPPort = vmixTCPPort
+			}
+			return ep, ep.Host != ""
+		}
+	}
+	if t.Host == "" || t.Port == 0 {
+		return endpoint{}, false // HostかPortがゼロ値の場合何もしない
+	}
+	// 旧設定のPortはHTTP APIのポートなので、TCP APIは既定のポートに接続する
+	return endpoint{Host: t.Host, TCPPort: vmixTCPPort}, true
+}
@@ -0,0 +1,55 @@
+package stdvmix
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	// backoffMin first reconnect delay
+	backoffMin = time.Millisecond * 500
+
+	// backoffMax reconnect delay cap
+	backoffMax = time.Second * 30
+)
+
+// connState connection state of a vMix session
+type connState int
+
+const (
+	// connConnecting dialing vMix
+	connConnecting connState = iota
+	// connOnline connected and subscribed
+	connOnline
+	// connOffline connection dropped
+	connOffline
+	// connBackoff waiting before the next attempt
+	connBackoff
+)
+
+func (c connState) String() string {
+	switch c {
+	case connConnecting:
+		return "connecting"
+	case connOnline:
+		return "online"
+	case connOffline:
+		return "offline"
+	case connBackoff:
+		return "backoff"
+	}
+	return "unknown"
+}
+
+// backoffDelay exponential backoff with jitter for the given number of consecutive failures.
+// Returns a random duration in [d/2, d] so hosts that went down together do not retry in lockstep.
+func backoffDelay(failures int) time.Duration {
+	d := backoffMax
+	if failures < 16 {
+		d = backoffMin << (failures - 1)
+	}
+	if d > backoffMax || d <= 0 {
+		d = backoffMax
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
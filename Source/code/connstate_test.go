@@ -0,0 +1,27 @@
+package stdvmix
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	for _, tc := range []struct {
+		failures int
+		max      time.Duration
+	}{
+		{1, backoffMin},
+		{2, backoffMin * 2},
+		{3, backoffMin * 4},
+		{7, backoffMax},
+		{16, backoffMax},
+		{100, backoffMax},
+	} {
+		for i := 0; i < 100; i++ {
+			d := backoffDelay(tc.failures)
+			if d < tc.max/2 || d > tc.max {
+				t.Fatalf("backoffDelay(%d) = %v, want [%v, %v]", tc.failures, d, tc.max/2, tc.max)
+			}
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package stdvmix
+
+import (
+	"context"
+	"time"
+)
+
+// countdownInterval XML refresh while countdown keys are shown.
+// The remaining time is only in the XML and ACTS does not report it. vMix shows it in seconds.
+const countdownInterval = time.Second
+
+// countdownLoop refresh XML of every vMix that has countdown keys until ctx is cancelled.
+// Keys are rendered by Update when the XML arrives.
+func (s *StdVmix) countdownLoop(ctx context.Context) {
+	ticker := time.NewTicker(countdownInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		refresh := map[string]vmixTarget{}
+		s.countdownContexts.Range(func(_, value any) bool {
+			if pi, ok := value.(CountdownPI); ok {
+				if ep, ok := s.store.Resolve(pi.Target()); ok {
+					refresh[ep.Addr()] = pi.Target()
+				}
+			}
+			return true
+		})
+		for _, t := range refresh {
+			s.store.Refresh(t)
+		}
+	}
+}
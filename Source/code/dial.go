@@ -0,0 +1,35 @@
+package stdvmix
+
+import (
+	"github.com/FlowingSPDG/streamdeck"
+)
+
+const (
+	// Stream Deck+ encoder events. The streamdeck package does not define them yet
+	eventDialRotate = "dialRotate"
+	eventDialPress  = "dialPress"
+	eventTouchTap   = "touchTap"
+)
+
+// DialRotatePayload dialRotate payload. Ticks is negative when rotated counterclockwise
+type DialRotatePayload[T any] struct {
+	Settings    T                      `json:"settings,omitempty"`
+	Coordinates streamdeck.Coordinates `json:"coordinates,omitempty"`
+	Ticks       int                    `json:"ticks"`
+	Pressed     bool                   `json:"pressed"`
+}
+
+// DialPressPayload dialPress payload. Sent for both press and release
+type DialPressPayload[T any] struct {
+	Settings    T                      `json:"settings,omitempty"`
+	Coordinates streamdeck.Coordinates `json:"coordinates,omitempty"`
+	Pressed     bool                   `json:"pressed"`
+}
+
+// TouchTapPayload touchTap payload. TapPos is the position on the touch strip
+type TouchTapPayload[T any] struct {
+	Settings    T                      `json:"settings,omitempty"`
+	Coordinates streamdeck.Coordinates `json:"coordinates,omitempty"`
+	TapPos      [2]int                 `json:"tapPos"`
+	Hold        bool                   `json:"hold"`
+}
@@ -0,0 +1,108 @@
+package stdvmix
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/FlowingSPDG/streamdeck"
+	sdcontext "github.com/FlowingSPDG/streamdeck/context"
+)
+
+const (
+	// confirm modes for dangerous functions
+	confirmNone   = ""
+	confirmHold   = "hold"   // hold the key for ConfirmTime
+	confirmDouble = "double" // press again within ConfirmTime
+
+	// defaultConfirmTime used when ConfirmTime is not set
+	defaultConfirmTime = time.Second
+
+	// armedTitle key title while waiting for confirmation
+	armedTitle = "ARMED"
+)
+
+// confirmTime hold time or double press window from the confirm_time setting in ms
+func confirmTime(ms int) time.Duration {
+	if ms <= 0 {
+		return defaultConfirmTime
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// confirmKeyDown run exec on keyDown as the confirm mode says.
+// hold: run once the key is held for d. double: run on the second press within d. Otherwise run now.
+func (s *StdVmix) confirmKeyDown(ctx context.Context, client *streamdeck.Client, mode string, d time.Duration, exec func(ctx context.Context) error) error {
+	switch mode {
+	case confirmHold:
+		// 押し続けている間に時間が経ったら実行する。KeyUpで解除
+		s.arm(ctx, d, func(ctx context.Context) {
+			if err := exec(ctx); err != nil {
+				client.LogMessage(fmt.Sprintf("Failed to execute after hold:%v", err))
+			}
+		})
+		return nil
+	case confirmDouble:
+		// 時間内の2回目の押下で実行する
+		if s.disarm(ctx) {
+			return exec(ctx)
+		}
+		s.arm(ctx, d, nil)
+		return nil
+	}
+	return exec(ctx)
+}
+
+// confirmKeyUp releasing before the hold time cancels the hold
+func (s *StdVmix) confirmKeyUp(ctx context.Context, mode string) {
+	if mode == confirmHold {
+		s.disarm(ctx)
+	}
+}
+
+// armedKey timer waiting for confirmation
+type armedKey struct {
+	timer *time.Timer
+}
+
+// arm show the armed state on the key and run fn after d unless disarmed first.
+// fn may be nil to just drop the armed state.
+// Handler contexts are cancelled when the handler returns, so fn gets a fresh one.
+func (s *StdVmix) arm(ctx context.Context, d time.Duration, fn func(ctx context.Context)) {
+	ctxStr := sdcontext.Context(ctx)
+	s.disarm(ctx)
+
+	a := &armedKey{}
+	s.armed.Store(ctxStr, a)
+	a.timer = time.AfterFunc(d, func() {
+		// 既に解除されているか、別のタイマーに置き換わっている場合は何もしない
+		if v, ok := s.armed.Load(ctxStr); !ok || v != a {
+			return
+		}
+		s.armed.Delete(ctxStr)
+		ctx := sdcontext.WithContext(context.Background(), ctxStr)
+		s.restoreArmed(ctx)
+		if fn != nil {
+			fn(ctx)
+		}
+	})
+	s.setImage(ctx, tallyArmed)
+	s.setTitle(ctx, armedTitle)
+}
+
+// disarm cancel the armed state. Returns true if the key was armed.
+func (s *StdVmix) disarm(ctx context.Context) bool {
+	v, ok := s.armed.LoadAndDelete(sdcontext.Context(ctx))
+	if !ok {
+		return false
+	}
+	v.(*armedKey).timer.Stop()
+	s.restoreArmed(ctx)
+	return true
+}
+
+// restoreArmed 空文字でマニフェストの画像とユーザーのタイトルに戻す
+func (s *StdVmix) restoreArmed(ctx context.Context) {
+	s.setImage(ctx, "")
+	s.setTitle(ctx, "")
+}
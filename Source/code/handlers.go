@@ -4,82 +4,192 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 
 	"github.com/FlowingSPDG/streamdeck"
+	sdcontext "github.com/FlowingSPDG/streamdeck/context"
 )
 
-// SendFuncWillAppearHandler willAppear handler.
-func (s *StdVmix) SendFuncWillAppearHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
-	p := streamdeck.WillAppearPayload[SendFunctionPI]{}
+// DidReceiveGlobalSettingsHandler didReceiveGlobalSettings handler. Re-binds every key to the updated connection profiles and tally colors.
+func (s *StdVmix) DidReceiveGlobalSettingsHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	p := streamdeck.DidReceiveGlobalSettingsPayload[GlobalSettings]{}
 	if err := json.Unmarshal(event.Payload, &p); err != nil {
 		return err
 	}
-
-	if p.Settings.IsDefault() {
-		p.Settings.Initialize()
-		msg := fmt.Sprintf("Forcing Default value:%v", p.Settings)
-		client.LogMessage(msg)
-		if err := client.SetSettings(ctx, p.Settings); err != nil {
-			return err
+	s.store.SetConnections(p.Settings.Connections)
+	s.tally.SetGlobal(p.Settings.Tally)
+	// 接続プロファイルを知る前に読んだ古い設定を読み直して移行する
+	s.legacy.Range(func(key, value any) bool {
+		s.legacy.Delete(key)
+		if err := client.GetSettings(sdcontext.WithContext(ctx, key.(string))); err != nil {
+			client.LogMessage(fmt.Sprintf("Failed to get settings:%v", err))
 		}
-	} else {
-		s.sendFuncContexts.Store(event.Context, p.Settings)
-	}
+		return true
+	})
+	s.sync()
 	return nil
 }
 
-// PreviewWillAppearHandler willAppear handler.
-func (s *StdVmix) PreviewWillAppearHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
-	p := streamdeck.WillAppearPayload[PreviewPI]{}
+// SystemDidWakeUpHandler systemDidWakeUp handler. Renders every key again.
+func (s *StdVmix) SystemDidWakeUpHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	s.rendered.forgetAll()
+	s.sync()
+	return nil
+}
+
+// PropertyInspectorDidAppearHandler propertyInspectorDidAppear handler. The input list is sent on the next update.
+func (s *StdVmix) PropertyInspectorDidAppearHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	s.inspectors.Store(event.Context, nil) // 前に開いたときに送ったものと同じでも送り直す
+	s.sync()
+	return nil
+}
+
+// SendToPluginHandler sendToPlugin handler.
+// The PI sends propertyInspectorConnected once its websocket is registered. Anything pushed before that is lost, so the list is sent again.
+func (s *StdVmix) SendToPluginHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	p := map[string]any{}
 	if err := json.Unmarshal(event.Payload, &p); err != nil {
 		return err
 	}
+	if p["property_inspector"] != "propertyInspectorConnected" {
+		return nil
+	}
+	s.inspectors.Store(event.Context, nil)
+	s.sync()
+	return nil
+}
+
+// PropertyInspectorDidDisappearHandler propertyInspectorDidDisappear handler.
+func (s *StdVmix) PropertyInspectorDidDisappearHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	s.inspectors.Delete(event.Context)
+	return nil
+}
+
+// loadSettings decode, clean up and migrate action settings the same way for every event.
+// Migrated settings are saved back. Settings only cleaned up are not, so a value being typed in the PI is left as is.
+func loadSettings[T any, PT interface {
+	*T
+	Initialize()
+}](ctx context.Context, s *StdVmix, client *streamdeck.Client, raw json.RawMessage) (T, error) {
+	conns, loaded := s.store.Connections()
+	settings, version, err := migrateSettings[T, PT](raw, migrationEnv{connections: conns})
+	if err != nil {
+		return settings, err
+	}
+	if version < settingsVersion {
+		if version == 0 && !loaded {
+			// 接続プロファイルを読み込む前は保存しない。グローバル設定を受け取ったら読み直す
+			s.legacy.Store(sdcontext.Context(ctx), struct{}{})
+			return settings, nil
+		}
+		client.LogMessage(fmt.Sprintf("Migrated settings from version %d:%v", version, settings))
+		if err := client.SetSettings(ctx, settings); err != nil {
+			return settings, err
+		}
+	}
+	return settings, nil
+}
 
-	if p.Settings.IsDefault() {
-		p.Settings.Initialize()
-		msg := fmt.Sprintf("Forcing Default value:%v", p.Settings)
-		client.LogMessage(msg)
-		if err := client.SetSettings(ctx, p.Settings); err != nil {
+// willAppearHandler willAppear handler of an action whose keys are kept in contexts
+func willAppearHandler[T any, PT interface {
+	*T
+	Initialize()
+}](s *StdVmix, contexts *sync.Map) streamdeck.EventHandler {
+	return func(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+		p := streamdeck.WillAppearPayload[json.RawMessage]{}
+		if err := json.Unmarshal(event.Payload, &p); err != nil {
 			return err
 		}
-	} else {
-		s.previewContexts.Store(event.Context, p.Settings)
+		settings, err := loadSettings[T, PT](ctx, s, client, p.Settings)
+		if err != nil {
+			return err
+		}
+		s.rendered.forget(event.Context) // 表示し直す
+		s.keyErrors.Delete(event.Context)
+		contexts.Store(event.Context, settings)
+		s.sync()
+		return nil
 	}
-	return nil
 }
 
-// PreviewWillAppearHandler willAppear handler.
-func (s *StdVmix) ProgramWillAppearHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
-	p := streamdeck.WillAppearPayload[ProgramPI]{}
-	if err := json.Unmarshal(event.Payload, &p); err != nil {
-		return err
+// willDisappearHandler willDisappear handler of an action whose keys are kept in contexts.
+// Everything kept for the Context is dropped so the key starts over when it appears again. A running macro is left to finish.
+func willDisappearHandler(s *StdVmix, contexts *sync.Map) streamdeck.EventHandler {
+	return func(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+		contexts.Delete(event.Context)
+		s.offline.Delete(event.Context)
+		s.rendered.forget(event.Context)
+		if v, ok := s.armed.LoadAndDelete(event.Context); ok {
+			v.(*armedKey).timer.Stop()
+		}
+		s.volumes.Delete(event.Context)
+		s.faders.Delete(event.Context)
+		s.titles.Delete(event.Context)
+		s.legacy.Delete(event.Context)
+		s.keyErrors.Delete(event.Context)
+		s.inspectors.Delete(event.Context)
+		s.sync()
+		return nil
 	}
+}
 
-	if p.Settings.IsDefault() {
-		p.Settings.Initialize()
-		msg := fmt.Sprintf("Forcing Default value:%v", p.Settings)
-		client.LogMessage(msg)
-		if err := client.SetSettings(ctx, p.Settings); err != nil {
+// didReceiveSettingsHandler didReceiveSettings handler of an action whose keys are kept in contexts
+func didReceiveSettingsHandler[T any, PT interface {
+	*T
+	Initialize()
+}](s *StdVmix, contexts *sync.Map) streamdeck.EventHandler {
+	return func(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+		p := streamdeck.DidReceiveSettingsPayload[json.RawMessage]{}
+		if err := json.Unmarshal(event.Payload, &p); err != nil {
 			return err
 		}
-	} else {
-		s.programContexts.Store(event.Context, p.Settings)
+		settings, err := loadSettings[T, PT](ctx, s, client, p.Settings)
+		if err != nil {
+			return err
+		}
+		s.keyErrors.Delete(event.Context) // 設定が変わったのでエラーもまたログに出す
+		contexts.Store(event.Context, settings)
+		s.sync()
+		return nil
 	}
-	return nil
 }
 
 // SendFuncKeyDownHandler keyDown handler
 func (s *StdVmix) SendFuncKeyDownHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
-	p := streamdeck.KeyDownPayload[SendFunctionPI]{}
+	p := streamdeck.KeyDownPayload[json.RawMessage]{}
 	if err := json.Unmarshal(event.Payload, &p); err != nil {
+		return err
+	}
+	settings, err := loadSettings[SendFunctionPI](ctx, s, client, p.Settings)
+	if err != nil {
 		client.ShowAlert(ctx)
 		return err
 	}
 
 	client.LogMessage("KeyDownHandler")
-	client.LogMessage(fmt.Sprintf("settings for this context:%v", p.Settings))
+	client.LogMessage(fmt.Sprintf("settings for this context:%v", settings))
+
+	return s.confirmKeyDown(ctx, client, settings.Confirm, confirmTime(settings.ConfirmTime), func(ctx context.Context) error {
+		return s.sendFuncExecute(ctx, client, settings)
+	})
+}
+
+// SendFuncKeyUpHandler keyUp handler. Releasing before the hold time cancels the function.
+func (s *StdVmix) SendFuncKeyUpHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	p := streamdeck.KeyUpPayload[json.RawMessage]{}
+	if err := json.Unmarshal(event.Payload, &p); err != nil {
+		return err
+	}
+	settings, err := loadSettings[SendFunctionPI](ctx, s, client, p.Settings)
+	if err != nil {
+		return err
+	}
+	s.confirmKeyUp(ctx, settings.Confirm)
+	return nil
+}
 
-	if err := p.Settings.Execute(); err != nil {
+func (s *StdVmix) sendFuncExecute(ctx context.Context, client *streamdeck.Client, pi SendFunctionPI) error {
+	if err := pi.Execute(ctx, s.store); err != nil {
 		client.ShowAlert(ctx)
 		return err
 	}
@@ -88,15 +198,20 @@ func (s *StdVmix) SendFuncKeyDownHandler(ctx context.Context, client *streamdeck
 
 // PreviewKeyDownHandler keyDown handler
 func (s *StdVmix) PreviewKeyDownHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
-	p := streamdeck.KeyDownPayload[PreviewPI]{}
+	p := streamdeck.KeyDownPayload[json.RawMessage]{}
 	if err := json.Unmarshal(event.Payload, &p); err != nil {
 		return err
 	}
+	settings, err := loadSettings[PreviewPI](ctx, s, client, p.Settings)
+	if err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
 
 	client.LogMessage("KeyDownHandler")
-	client.LogMessage(fmt.Sprintf("settings for this context:%v", p.Settings))
+	client.LogMessage(fmt.Sprintf("settings for this context:%v", settings))
 
-	if err := p.Settings.Execute(); err != nil {
+	if err := settings.Execute(ctx, s.store); err != nil {
 		client.ShowAlert(ctx)
 		return err
 	}
@@ -105,44 +220,346 @@ func (s *StdVmix) PreviewKeyDownHandler(ctx context.Context, client *streamdeck.
 
 // ProgramKeyDownHandler keyDown handler
 func (s *StdVmix) ProgramKeyDownHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
-	p := streamdeck.KeyDownPayload[ProgramPI]{}
+	p := streamdeck.KeyDownPayload[json.RawMessage]{}
 	if err := json.Unmarshal(event.Payload, &p); err != nil {
 		return err
 	}
+	settings, err := loadSettings[ProgramPI](ctx, s, client, p.Settings)
+	if err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
 
 	client.LogMessage("KeyDownHandler")
-	client.LogMessage(fmt.Sprintf("settings for this context:%v", p.Settings))
+	client.LogMessage(fmt.Sprintf("settings for this context:%v", settings))
 
-	if err := p.Settings.Execute(); err != nil {
+	if err := settings.Execute(ctx, s.store); err != nil {
 		client.ShowAlert(ctx)
 		return err
 	}
 	return client.ShowOk(ctx)
 }
 
-func (s *StdVmix) SendFuncDidReceiveSettingsHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
-	p := streamdeck.DidReceiveSettingsPayload[SendFunctionPI]{}
+// OverlayKeyDownHandler keyDown handler
+func (s *StdVmix) OverlayKeyDownHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	p := streamdeck.KeyDownPayload[json.RawMessage]{}
 	if err := json.Unmarshal(event.Payload, &p); err != nil {
 		return err
 	}
-	s.sendFuncContexts.Store(event.Context, p.Settings)
+	settings, err := loadSettings[OverlayPI](ctx, s, client, p.Settings)
+	if err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+
+	client.LogMessage("KeyDownHandler")
+	client.LogMessage(fmt.Sprintf("settings for this context:%v", settings))
+
+	if err := settings.Execute(ctx, s.store); err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+	return client.ShowOk(ctx)
+}
+
+// TransitionKeyDownHandler keyDown handler
+func (s *StdVmix) TransitionKeyDownHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	p := streamdeck.KeyDownPayload[json.RawMessage]{}
+	if err := json.Unmarshal(event.Payload, &p); err != nil {
+		return err
+	}
+	settings, err := loadSettings[TransitionPI](ctx, s, client, p.Settings)
+	if err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+
+	client.LogMessage("KeyDownHandler")
+	client.LogMessage(fmt.Sprintf("settings for this context:%v", settings))
+
+	if err := settings.Execute(ctx, s.store); err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+	return client.ShowOk(ctx)
+}
+
+// OutputKeyDownHandler keyDown handler
+func (s *StdVmix) OutputKeyDownHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	p := streamdeck.KeyDownPayload[json.RawMessage]{}
+	if err := json.Unmarshal(event.Payload, &p); err != nil {
+		return err
+	}
+	settings, err := loadSettings[OutputPI](ctx, s, client, p.Settings)
+	if err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+
+	client.LogMessage("KeyDownHandler")
+	client.LogMessage(fmt.Sprintf("settings for this context:%v", settings))
+
+	return s.confirmKeyDown(ctx, client, settings.Confirm, confirmTime(settings.ConfirmTime), func(ctx context.Context) error {
+		if err := settings.Execute(ctx, s.store); err != nil {
+			client.ShowAlert(ctx)
+			return err
+		}
+		return client.ShowOk(ctx)
+	})
+}
+
+// OutputKeyUpHandler keyUp handler. Releasing before the hold time cancels the output change.
+func (s *StdVmix) OutputKeyUpHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	p := streamdeck.KeyUpPayload[json.RawMessage]{}
+	if err := json.Unmarshal(event.Payload, &p); err != nil {
+		return err
+	}
+	settings, err := loadSettings[OutputPI](ctx, s, client, p.Settings)
+	if err != nil {
+		return err
+	}
+	s.confirmKeyUp(ctx, settings.Confirm)
 	return nil
 }
 
-func (s *StdVmix) PreviewDidReceiveSettingsHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
-	p := streamdeck.DidReceiveSettingsPayload[PreviewPI]{}
+// MacroKeyDownHandler keyDown handler. Pressing while running cancels the macro.
+func (s *StdVmix) MacroKeyDownHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	p := streamdeck.KeyDownPayload[json.RawMessage]{}
 	if err := json.Unmarshal(event.Payload, &p); err != nil {
 		return err
 	}
-	s.previewContexts.Store(event.Context, p.Settings)
+	settings, err := loadSettings[MacroPI](ctx, s, client, p.Settings)
+	if err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+
+	client.LogMessage("KeyDownHandler")
+	client.LogMessage(fmt.Sprintf("settings for this context:%v", settings))
+
+	steps, err := settings.ParseSteps()
+	if err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+	s.runMacro(ctx, settings, steps)
 	return nil
 }
 
-func (s *StdVmix) ProgramDidReceiveSettingsHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
-	p := streamdeck.DidReceiveSettingsPayload[ProgramPI]{}
+// AudioKeyDownHandler keyDown handler
+func (s *StdVmix) AudioKeyDownHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	p := streamdeck.KeyDownPayload[json.RawMessage]{}
 	if err := json.Unmarshal(event.Payload, &p); err != nil {
 		return err
 	}
-	s.programContexts.Store(event.Context, p.Settings)
+	settings, err := loadSettings[AudioPI](ctx, s, client, p.Settings)
+	if err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+
+	client.LogMessage("KeyDownHandler")
+	client.LogMessage(fmt.Sprintf("settings for this context:%v", settings))
+
+	if settings.Mode == audioStep {
+		err = s.stepVolume(ctx, settings, 1)
+	} else {
+		err = settings.Execute(ctx, s.store)
+	}
+	if err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+	return client.ShowOk(ctx)
+}
+
+// AudioDialRotateHandler dialRotate handler. Each tick adds Step
+func (s *StdVmix) AudioDialRotateHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	p := DialRotatePayload[json.RawMessage]{}
+	if err := json.Unmarshal(event.Payload, &p); err != nil {
+		return err
+	}
+	settings, err := loadSettings[AudioPI](ctx, s, client, p.Settings)
+	if err != nil {
+		return err
+	}
+	if err := s.stepVolume(ctx, settings, p.Ticks); err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+	return nil
+}
+
+// AudioDialPressHandler dialPress handler. Toggles mute on press
+func (s *StdVmix) AudioDialPressHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	p := DialPressPayload[json.RawMessage]{}
+	if err := json.Unmarshal(event.Payload, &p); err != nil {
+		return err
+	}
+	settings, err := loadSettings[AudioPI](ctx, s, client, p.Settings)
+	if err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+	if !p.Pressed {
+		return nil
+	}
+	if err := settings.ToggleMute(ctx, s.store); err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+	s.store.Refresh(settings.Target())
+	return nil
+}
+
+// AudioTouchTapHandler touchTap handler. Sets Volume
+func (s *StdVmix) AudioTouchTapHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	p := TouchTapPayload[json.RawMessage]{}
+	if err := json.Unmarshal(event.Payload, &p); err != nil {
+		return err
+	}
+	settings, err := loadSettings[AudioPI](ctx, s, client, p.Settings)
+	if err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+	if err := settings.SetVolume(ctx, s.store, float64(settings.Volume)); err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+	s.volumes.Delete(event.Context)
+	s.store.Refresh(settings.Target())
+	return nil
+}
+
+// AudioRoutingKeyDownHandler keyDown handler
+func (s *StdVmix) AudioRoutingKeyDownHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	p := streamdeck.KeyDownPayload[json.RawMessage]{}
+	if err := json.Unmarshal(event.Payload, &p); err != nil {
+		return err
+	}
+	settings, err := loadSettings[AudioRoutingPI](ctx, s, client, p.Settings)
+	if err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+
+	client.LogMessage("KeyDownHandler")
+	client.LogMessage(fmt.Sprintf("settings for this context:%v", settings))
+
+	if err := settings.Execute(ctx, s.store); err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+	s.store.Refresh(settings.Target())
+	return client.ShowOk(ctx)
+}
+
+// TBarDialRotateHandler dialRotate handler. Moves the T-Bar
+func (s *StdVmix) TBarDialRotateHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	p := DialRotatePayload[json.RawMessage]{}
+	if err := json.Unmarshal(event.Payload, &p); err != nil {
+		return err
+	}
+	settings, err := loadSettings[TBarPI](ctx, s, client, p.Settings)
+	if err != nil {
+		return err
+	}
+	if err := s.moveFader(ctx, settings, p.Ticks); err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
 	return nil
 }
+
+// TBarDialPressHandler dialPress handler. Completes or auto-transitions
+func (s *StdVmix) TBarDialPressHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	p := DialPressPayload[json.RawMessage]{}
+	if err := json.Unmarshal(event.Payload, &p); err != nil {
+		return err
+	}
+	settings, err := loadSettings[TBarPI](ctx, s, client, p.Settings)
+	if err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+	if !p.Pressed {
+		return nil
+	}
+	if err := settings.Execute(ctx, s.store); err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+	s.faders.Store(event.Context, 0)
+	s.renderFader(ctx, 0)
+	return nil
+}
+
+// TitleKeyDownHandler keyDown handler
+func (s *StdVmix) TitleKeyDownHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	p := streamdeck.KeyDownPayload[json.RawMessage]{}
+	if err := json.Unmarshal(event.Payload, &p); err != nil {
+		return err
+	}
+	settings, err := loadSettings[TitlePI](ctx, s, client, p.Settings)
+	if err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+
+	client.LogMessage("KeyDownHandler")
+	client.LogMessage(fmt.Sprintf("settings for this context:%v", settings))
+
+	if err := s.setTitleField(ctx, settings); err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+	return client.ShowOk(ctx)
+}
+
+// CountdownKeyDownHandler keyDown handler
+func (s *StdVmix) CountdownKeyDownHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	p := streamdeck.KeyDownPayload[json.RawMessage]{}
+	if err := json.Unmarshal(event.Payload, &p); err != nil {
+		return err
+	}
+	settings, err := loadSettings[CountdownPI](ctx, s, client, p.Settings)
+	if err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+
+	client.LogMessage("KeyDownHandler")
+	client.LogMessage(fmt.Sprintf("settings for this context:%v", settings))
+
+	if err := settings.Execute(ctx, s.store); err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+	s.store.Refresh(settings.Target())
+	return client.ShowOk(ctx)
+}
+
+// ReplayKeyDownHandler keyDown handler
+func (s *StdVmix) ReplayKeyDownHandler(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
+	p := streamdeck.KeyDownPayload[json.RawMessage]{}
+	if err := json.Unmarshal(event.Payload, &p); err != nil {
+		return err
+	}
+	settings, err := loadSettings[ReplayPI](ctx, s, client, p.Settings)
+	if err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+
+	client.LogMessage("KeyDownHandler")
+	client.LogMessage(fmt.Sprintf("settings for this context:%v", settings))
+
+	if err := settings.Execute(ctx, s.store); err != nil {
+		client.ShowAlert(ctx)
+		return err
+	}
+	// 再生速度などはACTSで通知されないので取り直す
+	s.store.Refresh(settings.Target())
+	return client.ShowOk(ctx)
+}
@@ -0,0 +1,105 @@
+package stdvmix
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/FlowingSPDG/streamdeck"
+)
+
+const (
+	// keySize Stream Deck key image size (same as the tally PNGs)
+	keySize = 72
+
+	// offlineTitle key title while vMix is unreachable
+	offlineTitle = "OFFLINE"
+)
+
+var (
+	// tallyOffline dark key with an amber frame, shown while vMix is unreachable
+	tallyOffline = mustImage(frameImage(color.RGBA{0x20, 0x20, 0x20, 0xff}, color.RGBA{0xff, 0xa0, 0x00, 0xff}, 6))
+
+	// tallyArmed amber key, shown while a guarded function waits for confirmation
+	tallyArmed = mustImage(frameImage(color.RGBA{0xff, 0xa0, 0x00, 0xff}, color.RGBA{0xff, 0xff, 0xff, 0xff}, 3))
+)
+
+// frameImage key filled with bg and a border of the given width
+func frameImage(bg, border color.Color, width int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, keySize, keySize))
+	draw.Draw(img, img.Bounds(), image.NewUniform(border), image.Point{}, draw.Src)
+	inner := image.Rect(width, width, keySize-width, keySize-width)
+	draw.Draw(img, inner, image.NewUniform(bg), image.Point{}, draw.Src)
+	return img
+}
+
+// levelImage vertical volume bar. Volume is rounded so each step is one image
+func levelImage(volume float64, muted bool) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, keySize, keySize))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{0x20, 0x20, 0x20, 0xff}), image.Point{}, draw.Src)
+
+	var bar color.Color = color.RGBA{0x00, 0xc0, 0x40, 0xff}
+	if muted {
+		bar = color.RGBA{0x60, 0x60, 0x60, 0xff}
+	}
+	const margin = 8
+	height := int(math.Round(volume)) * (keySize - margin*2) / 100
+	track := image.Rect(keySize/2-8, margin, keySize/2+8, keySize-margin)
+	draw.Draw(img, track, image.NewUniform(color.RGBA{0x40, 0x40, 0x40, 0xff}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(track.Min.X, track.Max.Y-height, track.Max.X, track.Max.Y), image.NewUniform(bar), image.Point{}, draw.Src)
+	return img
+}
+
+// faderImage horizontal T-Bar position. pos 0-1
+func faderImage(pos float64) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, keySize, keySize))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{0x20, 0x20, 0x20, 0xff}), image.Point{}, draw.Src)
+
+	const margin = 8
+	track := image.Rect(margin, keySize/2-8, keySize-margin, keySize/2+8)
+	draw.Draw(img, track, image.NewUniform(color.RGBA{0x40, 0x40, 0x40, 0xff}), image.Point{}, draw.Src)
+	width := int(math.Round(pos * float64(track.Dx())))
+	draw.Draw(img, image.Rect(track.Min.X, track.Min.Y, track.Min.X+width, track.Max.Y), image.NewUniform(color.RGBA{0xff, 0x20, 0x20, 0xff}), image.Point{}, draw.Src)
+	return img
+}
+
+// meterImage stereo level meter. l and r are bar heights 0-1
+func meterImage(l, r float64) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, keySize, keySize))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{0x10, 0x10, 0x10, 0xff}), image.Point{}, draw.Src)
+
+	const margin = 6
+	const width = 24
+	height := keySize - margin*2
+	for i, level := range []float64{l, r} {
+		x := keySize/2 - width - 2 + i*(width+4)
+		lit := int(math.Round(level * float64(height)))
+		// 下から1pxずつ塗る。上に行くほど黄色、赤になる
+		for y := 0; y < height; y++ {
+			c := color.RGBA{0x30, 0x30, 0x30, 0xff}
+			if y < lit {
+				switch {
+				case y >= height*19/20: // -3dB
+					c = color.RGBA{0xff, 0x20, 0x20, 0xff}
+				case y >= height*4/5: // -12dB
+					c = color.RGBA{0xff, 0xd0, 0x00, 0xff}
+				default:
+					c = color.RGBA{0x00, 0xc0, 0x40, 0xff}
+				}
+			}
+			row := image.Rect(x, keySize-margin-y-1, x+width, keySize-margin-y)
+			draw.Draw(img, row, image.NewUniform(c), image.Point{}, draw.Src)
+		}
+	}
+	return img
+}
+
+// mustImage encode to data URI for SetImage
+func mustImage(img image.Image) string {
+	s, err := streamdeck.Image(img)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
@@ -0,0 +1,59 @@
+package stdvmix
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sdcontext "github.com/FlowingSPDG/streamdeck/context"
+)
+
+// runningMacro cancel func of a running macro
+type runningMacro struct {
+	cancel context.CancelFunc
+}
+
+// runMacro run steps in order on the shared session. Pressing the key again cancels the running macro.
+func (s *StdVmix) runMacro(ctx context.Context, pi MacroPI, steps []macroStep) {
+	ctxStr := sdcontext.Context(ctx)
+	if v, ok := s.macros.LoadAndDelete(ctxStr); ok {
+		v.(*runningMacro).cancel()
+		return
+	}
+
+	// ハンドラのctxは終了時にキャンセルされるので新しく作る
+	mctx, cancel := context.WithCancel(sdcontext.WithContext(context.Background(), ctxStr))
+	m := &runningMacro{cancel: cancel}
+	s.macros.Store(ctxStr, m)
+
+	go func() {
+		defer cancel()
+		defer func() {
+			if v, ok := s.macros.Load(ctxStr); ok && v == m {
+				s.macros.Delete(ctxStr)
+			}
+		}()
+
+		for i, step := range steps {
+			if err := s.store.Function(mctx, pi.Target(), step.Name, step.Params); err != nil {
+				if mctx.Err() != nil {
+					s.c.LogMessage(fmt.Sprintf("Macro cancelled at step %d (%s)", i+1, step.Name))
+					return
+				}
+				s.c.LogMessage(fmt.Sprintf("Macro failed at step %d (%s):%v", i+1, step.Name, err))
+				s.c.ShowAlert(mctx)
+				return
+			}
+			if step.Delay <= 0 {
+				continue
+			}
+			select {
+			case <-mctx.Done():
+				s.c.LogMessage(fmt.Sprintf("Macro cancelled after step %d (%s)", i+1, step.Name))
+				return
+			case <-time.After(step.Delay):
+			}
+		}
+		s.c.ShowOk(mctx)
+	}()
+}
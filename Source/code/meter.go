@@ -0,0 +1,78 @@
+package stdvmix
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	sdcontext "github.com/FlowingSPDG/streamdeck/context"
+)
+
+const (
+	// meterInterval frame interval of level meters. XML is requested at the same rate while meters are shown.
+	// The TCP API has no smaller poll than the full XML, and it shares the connection with FUNCTION replies, so keep it low.
+	meterInterval = time.Second / 4
+
+	// meterFloor lowest level drawn on meters(dB)
+	meterFloor = -60.0
+)
+
+// meterHeight level(0-1 amplitude) to 0-1 bar height on a dB scale
+func meterHeight(level float64) float64 {
+	if level <= 0 {
+		return 0
+	}
+	db := 20 * math.Log10(level)
+	return math.Max(0, math.Min(1, (db-meterFloor)/-meterFloor))
+}
+
+// meterLoop render every meter key at meterInterval until ctx is cancelled.
+// Meters are drawn from the shared snapshot, and unchanged frames are dropped by the render cache.
+// XML asked for meters does not redraw the other keys; they follow TALLY, ACTS and the periodic resync.
+// Meter keys leave meterContexts on WillDisappear, so nothing is polled while no meter key is on the current page.
+func (s *StdVmix) meterLoop(ctx context.Context) {
+	ticker := time.NewTicker(meterInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		refresh := map[string]vmixTarget{}
+		s.meterContexts.Range(func(key, value any) bool {
+			ctxStr := key.(string)
+			pi, ok := value.(MeterPI)
+			if !ok {
+				msg := fmt.Sprintf("Failed to cast value for meter. Actual:%s", reflect.TypeOf(value))
+				s.c.LogMessage(msg)
+				return true
+			}
+			ep, ok := s.store.Resolve(pi.Target())
+			if !ok {
+				return true
+			}
+			refresh[ep.Addr()] = pi.Target()
+
+			st, err := s.store.Get(ep.Addr())
+			if err != nil {
+				return true // オフライン表示はUpdateで行う
+			}
+			l, r, err := pi.UpdateMeter(st)
+			if err != nil {
+				return true
+			}
+			ctx := sdcontext.WithContext(context.Background(), ctxStr)
+			s.setImage(ctx, mustImage(meterImage(meterHeight(l), meterHeight(r))))
+			return true
+		})
+
+		// メーターはACTSで通知されないのでXMLを取り直す。描画は次のフレームで行う
+		for _, t := range refresh {
+			s.store.RefreshMeters(t)
+		}
+	}
+}
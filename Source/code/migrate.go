@@ -0,0 +1,123 @@
+package stdvmix
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// settingsVersion current version of action settings.
+// 0: settings saved before versioning (or a new key)
+// 1: connection profiles, confirm, etc. Missing fields are filled with defaults
+// 2: live input list is no longer saved
+const settingsVersion = 2
+
+// stringIntFields int fields saved with `,string`. Older payloads may have them as JSON numbers or "".
+var stringIntFields = []string{"version", "port", "confirm_time", "channel", "duration", "button", "volume", "fade", "step", "sensitivity", "number"}
+
+// migrationEnv what migrations can look up besides the settings themselves
+type migrationEnv struct {
+	connections []Connection // connection profiles from global settings
+}
+
+// settingsMigrations migrations[v] upgrades settings from version v to v+1
+var settingsMigrations = []func(m map[string]json.RawMessage, env migrationEnv) error{
+	// 0 -> 1: refer to the connection profile with the same host and port. Host/Port are kept as the fallback.
+	func(m map[string]json.RawMessage, env migrationEnv) error {
+		var conn, host, port string
+		json.Unmarshal(m["connection"], &conn)
+		json.Unmarshal(m["host"], &host)
+		json.Unmarshal(m["port"], &port)
+		if conn != "" || host == "" {
+			return nil
+		}
+		for _, c := range env.connections {
+			if c.Host == host && strconv.Itoa(c.Port) == port {
+				m["connection"] = json.RawMessage(strconv.Quote(c.ID))
+				return nil
+			}
+		}
+		return nil
+	},
+	// 1 -> 2: drop "inputs"
+	func(m map[string]json.RawMessage, env migrationEnv) error {
+		delete(m, "inputs")
+		return nil
+	},
+}
+
+// migrateSettings decode raw action settings and upgrade them to settingsVersion.
+// Decoding starts from Initialize() so only fields missing in the payload get default values and nothing the user set is wiped.
+// Int fields saved as numbers or cleared to "" in the PI are accepted as well.
+// Returns the version the settings were saved with. They should be saved with SetSettings if it is older than settingsVersion.
+func migrateSettings[T any, PT interface {
+	*T
+	Initialize()
+}](raw json.RawMessage, env migrationEnv) (T, int, error) {
+	var ret T
+	m := map[string]json.RawMessage{}
+	if len(raw) != 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return ret, 0, fmt.Errorf("Invalid settings:%w", err)
+		}
+	}
+	normalizeStringInts(m)
+
+	version, err := settingsVersionOf(m)
+	if err != nil {
+		return ret, 0, err
+	}
+	if version > settingsVersion {
+		return ret, version, fmt.Errorf("Unknown settings version:%d", version)
+	}
+	for v := version; v < settingsVersion; v++ {
+		if err := settingsMigrations[v](m, env); err != nil {
+			return ret, version, fmt.Errorf("Failed to migrate settings from version %d:%w", v, err)
+		}
+	}
+	m["version"] = json.RawMessage(strconv.Quote(strconv.Itoa(settingsVersion)))
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return ret, version, err
+	}
+	PT(&ret).Initialize()
+	if err := json.Unmarshal(b, &ret); err != nil {
+		return ret, version, fmt.Errorf("Invalid settings:%w", err)
+	}
+	return ret, version, nil
+}
+
+// settingsVersionOf version of raw settings. 0 if not set.
+func settingsVersionOf(m map[string]json.RawMessage) (int, error) {
+	raw, ok := m["version"]
+	if !ok {
+		return 0, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return 0, fmt.Errorf("Invalid settings version:%s", raw)
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid settings version:%s", s)
+	}
+	return v, nil
+}
+
+// normalizeStringInts quote int fields saved as numbers and drop empty ones so they get defaults.
+func normalizeStringInts(m map[string]json.RawMessage) {
+	for _, key := range stringIntFields {
+		raw, ok := m[key]
+		if !ok {
+			continue
+		}
+		var n json.Number
+		switch {
+		case string(raw) == `""` || string(raw) == "null":
+			delete(m, key)
+		case raw[0] != '"' && json.Unmarshal(raw, &n) == nil:
+			m[key] = json.RawMessage(strconv.Quote(n.String()))
+		}
+	}
+}
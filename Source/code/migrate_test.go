@@ -0,0 +1,86 @@
+package stdvmix
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMigrateSettings(t *testing.T) {
+	conns := []Connection{{ID: "studio", Host: "192.168.0.10", Port: 8088, TCPPort: 8099}}
+
+	for _, tc := range []struct {
+		name    string
+		raw     string
+		version int
+		want    func(p TransitionPI) bool
+	}{
+		{
+			name: "new key",
+			raw:  ``,
+			want: func(p TransitionPI) bool { return p.Duration == 500 && p.Effect == "Fade" },
+		},
+		{
+			name: "legacy settings keep what the user set",
+			raw:  `{"host":"192.168.0.20","port":"8088","effect":"Merge","inputs":[{"name":"Black"}]}`,
+			want: func(p TransitionPI) bool {
+				return p.Host == "192.168.0.20" && p.Effect == "Merge" && p.Duration == 500 && p.Connection == ""
+			},
+		},
+		{
+			name: "legacy host/port refers to the profile",
+			raw:  `{"host":"192.168.0.10","port":"8088"}`,
+			want: func(p TransitionPI) bool { return p.Connection == "studio" && p.Host == "192.168.0.10" },
+		},
+		{
+			name: "legacy port saved as number",
+			raw:  `{"host":"192.168.0.10","port":8088}`,
+			want: func(p TransitionPI) bool { return p.Connection == "studio" && p.Port == 8088 },
+		},
+		{
+			name:    "cleared int gets default",
+			raw:     `{"version":"2","duration":"","button":null}`,
+			version: 2,
+			want:    func(p TransitionPI) bool { return p.Duration == 500 && p.Button == 1 },
+		},
+		{
+			name:    "int saved as number",
+			raw:     `{"version":2,"duration":1000}`,
+			version: 2,
+			want:    func(p TransitionPI) bool { return p.Duration == 1000 },
+		},
+		{
+			name:    "profile set by the user is kept",
+			raw:     `{"version":"1","connection":"other","host":"192.168.0.10","port":"8088"}`,
+			version: 1,
+			want:    func(p TransitionPI) bool { return p.Connection == "other" },
+		},
+	} {
+		p, version, err := migrateSettings[TransitionPI](json.RawMessage(tc.raw), migrationEnv{connections: conns})
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if version != tc.version {
+			t.Errorf("%s: version = %d, want %d", tc.name, version, tc.version)
+		}
+		if p.Version != settingsVersion {
+			t.Errorf("%s: migrated to version %d", tc.name, p.Version)
+		}
+		if !tc.want(p) {
+			t.Errorf("%s: settings = %+v", tc.name, p)
+		}
+	}
+}
+
+func TestMigrateSettingsErrors(t *testing.T) {
+	for _, raw := range []string{
+		`{"version":"3"}`,
+		`{"version":"x"}`,
+		`{"duration":"abc"}`,
+		`[]`,
+	} {
+		if _, _, err := migrateSettings[TransitionPI](json.RawMessage(raw), migrationEnv{}); err == nil {
+			t.Errorf("%s: no error", raw)
+		}
+	}
+}
@@ -1,20 +1,26 @@
 package stdvmix
 
 import (
+	"context"
 	"fmt"
-	"reflect"
-
-	vmixhttp "github.com/FlowingSPDG/vmix-go/http"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // SendFunctionPI Settings for each button to save persistantly on action instance
 type SendFunctionPI struct {
-	Host    string  `json:"host"`
-	Port    int     `json:"port,string"`
-	Input   string  `json:"input"`
-	Inputs  []input `json:"inputs"`
-	Name    string  `json:"name"`
-	Queries []Query `json:"queries"`
+	Version     int     `json:"version,string"` // settingsVersion
+	Connection  string  `json:"connection"`     // Connection profile ID
+	Host        string  `json:"host"`
+	Port        int     `json:"port,string"`
+	Input       string  `json:"input"`
+	Name        string  `json:"name"`
+	Queries     []Query `json:"queries"`
+	Confirm     string  `json:"confirm"`             // "", "hold" or "double"
+	ConfirmTime int     `json:"confirm_time,string"` // ms
 }
 
 type Query struct {
@@ -22,205 +28,1029 @@ type Query struct {
 	Value string `json:"value"`
 }
 
-func (p SendFunctionPI) IsDefault() bool {
-	return reflect.ValueOf(p).IsZero()
-}
-
 func (p *SendFunctionPI) Initialize() {
+	p.Version = settingsVersion
 	p.Host = "localhost"
 	p.Port = 8088
 	p.Input = "0"
 	p.Name = "PreviewInput"
-	p.Inputs = []input{}
 	p.Queries = []Query{}
+	p.Confirm = confirmNone
+	p.ConfirmTime = int(defaultConfirmTime / time.Millisecond)
 }
 
-func (p SendFunctionPI) Execute() error {
-	vc, err := vmixhttp.NewClient(p.Host, p.Port)
-	if err != nil {
-		return err
-	}
+func (p SendFunctionPI) Execute(ctx context.Context, s *stateStore) error {
 	params := make(map[string]string)
 	for _, query := range p.Queries {
 		params[query.Key] = query.Value
 	}
-	return vc.SendFunction(p.Name, params)
+	return s.Function(ctx, p.Target(), p.Name, params)
 }
 
-// UpdateInputs 自身のInputsを更新する(本当は同じリクエストを何度も送りたくないのでキャッシュしたい)
-func (p *SendFunctionPI) UpdateInputs() error {
-	if p.Host == "" || p.Port == 0 {
-		return nil // HostかPortがゼロ値の場合何もしない
-	}
-	vc, err := vmixhttp.NewClient(p.Host, p.Port)
-	if err != nil {
-		return err
-	}
-	// スライスをリセットして更新
-	p.Inputs = make([]input, 0, len(vc.Inputs.Input))
-	for _, i := range vc.Inputs.Input {
-		p.Inputs = append(p.Inputs, input{
-			Name:   i.Name,
-			Key:    i.Key,
-			Number: int(i.Number),
-		})
-	}
-	return nil
+// Target vMix connection of this action
+func (p SendFunctionPI) Target() vmixTarget {
+	return vmixTarget{Connection: p.Connection, Host: p.Host, Port: p.Port}
 }
 
 // PreviewPI Property Inspector info for Preview
 type PreviewPI struct {
-	Host   string  `json:"host"`
-	Port   int     `json:"port,string"`
-	Input  string  `json:"input"`
-	Inputs []input `json:"inputs"`
-	Mix    string  `json:"mix"`
-	Tally  bool    `json:"tally"`
-}
+	Version    int    `json:"version,string"` // settingsVersion
+	Connection string `json:"connection"`     // Connection profile ID
+	Host       string `json:"host"`
+	Port       int    `json:"port,string"`
+	Input      string `json:"input"`
+	Mix        string `json:"mix"` // Mix parameter. "" for main, "1" for Mix 2 ...
+	Tally      bool   `json:"tally"`
+	InputRef   string `json:"input_ref"` // input number or name. Used instead of Input if set
+	Label      bool   `json:"label"`     // show input number and title on the key
 
-func (p PreviewPI) IsDefault() bool {
-	return reflect.ValueOf(p).IsZero()
+	TallyColors // empty fields use the global colors
 }
 
 func (p *PreviewPI) Initialize() {
+	p.Version = settingsVersion
 	p.Host = "localhost"
 	p.Port = 8088
 	p.Input = "0"
-	p.Inputs = []input{}
 	p.Mix = ""
 	p.Tally = false
+	p.InputRef = ""
+	p.Label = false
 }
 
-func (p PreviewPI) Execute() error {
-	vc, err := vmixhttp.NewClient(p.Host, p.Port)
-	if err != nil {
-		return err
-	}
+func (p PreviewPI) Execute(ctx context.Context, s *stateStore) error {
 	params := make(map[string]string)
-	params["Input"] = p.Input
-	params["Mix"] = p.Mix
-	return vc.SendFunction("PreviewInput", params)
+	params["Input"] = p.input()
+	if p.Mix != "" {
+		params["Mix"] = p.Mix
+	}
+	return s.Function(ctx, p.Target(), "PreviewInput", params)
 }
 
-// UpdateTally タリーを更新、点灯する必要がある場合trueが帰る
-func (p PreviewPI) UpdateTally() (bool, error) {
-	if p.Host == "" || p.Port == 0 {
-		return false, nil // HostかPortがゼロ値の場合何もしない
-	}
-	vc, err := vmixhttp.NewClient(p.Host, p.Port)
-	if err != nil {
-		return false, err
-	}
-	// 以下コードだとpanicが起きそう
-	// vc.Inputs.Input[vc.Preview-1].Key == p.Input, nil
-	for _, input := range vc.Inputs.Input {
-		// 一致するinputがあればtrueを返す
-		if input.Key != p.Input {
-			continue
-		}
-		return input.Number == vc.Preview, nil
+// Target vMix connection of this action
+func (p PreviewPI) Target() vmixTarget {
+	return vmixTarget{Connection: p.Connection, Host: p.Host, Port: p.Port}
+}
+
+// input Input parameter. key from the list, or number/name typed by the user
+func (p PreviewPI) input() string {
+	if p.InputRef != "" {
+		return p.InputRef
 	}
-	return false, fmt.Errorf("No input found")
+	return p.Input
 }
 
-// UpdateInputs 自身のInputsを更新する(本当は同じリクエストを何度も送りたくないのでキャッシュしたい)
-func (p *PreviewPI) UpdateInputs() error {
-	if p.Host == "" || p.Port == 0 {
-		return nil // HostかPortがゼロ値の場合何もしない
+// UpdateTally タリーを更新。プレビュー、プログラムどちらのキーでもvMixのタリーと同じ色になる
+// Mixが指定されている場合はそのMixのプレビュー、プログラムで判定する
+func (p PreviewPI) UpdateTally(st *vmixState) (tallyKind, error) {
+	input, ok := st.findInput(p.input())
+	if !ok {
+		return tallyOff, fmt.Errorf("No input found")
 	}
-	vc, err := vmixhttp.NewClient(p.Host, p.Port)
+	tally, err := st.tallyOfMix(p.Mix, input.Number)
 	if err != nil {
-		return err
+		return tallyOff, err
 	}
-	// スライスをリセットして更新
-	p.Inputs = make([]input, 0, len(vc.Inputs.Input))
-	for _, i := range vc.Inputs.Input {
-		p.Inputs = append(p.Inputs, input{
-			Name:   i.Name,
-			Key:    i.Key,
-			Number: int(i.Number),
-		})
+	return tallyKindOf(tally), nil
+}
+
+// UpdateLabel key title of the input
+func (p PreviewPI) UpdateLabel(st *vmixState) (string, error) {
+	input, ok := st.findInput(p.input())
+	if !ok {
+		return "", fmt.Errorf("No input found")
 	}
-	return nil
+	return input.label(), nil
 }
 
 // ProgramPI Property Inspector info for PGM(Cut)
 type ProgramPI struct {
-	Host      string  `json:"host"`
-	Port      int     `json:"port,string"`
-	Input     string  `json:"input"`
-	Inputs    []input `json:"inputs"`
-	Mix       string  `json:"mix"`
-	CutDirect bool    `json:"cut_direct"`
-	Tally     bool    `json:"tally"`
-}
+	Version    int    `json:"version,string"` // settingsVersion
+	Connection string `json:"connection"`     // Connection profile ID
+	Host       string `json:"host"`
+	Port       int    `json:"port,string"`
+	Input      string `json:"input"`
+	Mix        string `json:"mix"` // Mix parameter. "" for main, "1" for Mix 2 ...
+	CutDirect  bool   `json:"cut_direct"`
+	Tally      bool   `json:"tally"`
+	InputRef   string `json:"input_ref"` // input number or name. Used instead of Input if set
+	Label      bool   `json:"label"`     // show input number and title on the key
 
-func (p ProgramPI) IsDefault() bool {
-	return reflect.ValueOf(p).IsZero()
+	TallyColors // empty fields use the global colors
 }
 
 func (p *ProgramPI) Initialize() {
+	p.Version = settingsVersion
 	p.Host = "localhost"
 	p.Port = 8088
 	p.Input = "0"
-	p.Inputs = []input{}
 	p.Mix = ""
 	p.CutDirect = false
 	p.Tally = false
+	p.InputRef = ""
+	p.Label = false
 }
 
-func (p ProgramPI) Execute() error {
-	vc, err := vmixhttp.NewClient(p.Host, p.Port)
-	if err != nil {
-		return err
-	}
+func (p ProgramPI) Execute(ctx context.Context, s *stateStore) error {
 	cut := "Cut"
 	if p.CutDirect {
 		cut = "CutDirect"
 	}
 	params := make(map[string]string)
-	params["Input"] = p.Input
-	params["Mix"] = p.Mix
-	return vc.SendFunction(cut, params)
+	params["Input"] = p.input()
+	if p.Mix != "" {
+		params["Mix"] = p.Mix
+	}
+	return s.Function(ctx, p.Target(), cut, params)
 }
 
-// UpdateTally タリーを更新、点灯する必要がある場合trueが帰る
-func (p ProgramPI) UpdateTally() (bool, error) {
-	if p.Host == "" || p.Port == 0 {
-		return false, nil // HostかPortがゼロ値の場合何もしない
+// Target vMix connection of this action
+func (p ProgramPI) Target() vmixTarget {
+	return vmixTarget{Connection: p.Connection, Host: p.Host, Port: p.Port}
+}
+
+// input Input parameter. key from the list, or number/name typed by the user
+func (p ProgramPI) input() string {
+	if p.InputRef != "" {
+		return p.InputRef
 	}
-	vc, err := vmixhttp.NewClient(p.Host, p.Port)
+	return p.Input
+}
+
+// UpdateTally タリーを更新。プレビュー、プログラムどちらのキーでもvMixのタリーと同じ色になる
+// Mixが指定されている場合はそのMixのプレビュー、プログラムで判定する
+func (p ProgramPI) UpdateTally(st *vmixState) (tallyKind, error) {
+	input, ok := st.findInput(p.input())
+	if !ok {
+		return tallyOff, fmt.Errorf("No input found")
+	}
+	tally, err := st.tallyOfMix(p.Mix, input.Number)
+	if err != nil {
+		return tallyOff, err
+	}
+	return tallyKindOf(tally), nil
+}
+
+// UpdateLabel key title of the input
+func (p ProgramPI) UpdateLabel(st *vmixState) (string, error) {
+	input, ok := st.findInput(p.input())
+	if !ok {
+		return "", fmt.Errorf("No input found")
+	}
+	return input.label(), nil
+}
+
+const (
+	// overlay modes
+	overlayIn      = "In"
+	overlayOut     = "Out"
+	overlayToggle  = "Toggle"
+	overlayPreview = "Preview"
+)
+
+// OverlayPI Property Inspector info for Overlay channel
+type OverlayPI struct {
+	Version    int    `json:"version,string"` // settingsVersion
+	Connection string `json:"connection"`     // Connection profile ID
+	Host       string `json:"host"`
+	Port       int    `json:"port,string"`
+	Input      string `json:"input"`
+	Channel    int    `json:"channel,string"` // 1-4
+	Mode       string `json:"mode"`           // In, Out, Toggle, Preview
+	Tally      bool   `json:"tally"`
+
+	TallyColors // empty fields use the global colors
+}
+
+func (p *OverlayPI) Initialize() {
+	p.Version = settingsVersion
+	p.Host = "localhost"
+	p.Port = 8088
+	p.Input = "0"
+	p.Channel = 1
+	p.Mode = overlayToggle
+	p.Tally = false
+}
+
+// function OverlayInput1In, OverlayInput1Out, OverlayInput1, PreviewOverlayInput1...
+func (p OverlayPI) function() (string, error) {
+	if p.Channel < 1 || p.Channel > 4 {
+		return "", fmt.Errorf("Invalid overlay channel:%d", p.Channel)
+	}
+	switch p.Mode {
+	case overlayIn:
+		return fmt.Sprintf("OverlayInput%dIn", p.Channel), nil
+	case overlayOut:
+		return fmt.Sprintf("OverlayInput%dOut", p.Channel), nil
+	case overlayToggle:
+		return fmt.Sprintf("OverlayInput%d", p.Channel), nil
+	case overlayPreview:
+		return fmt.Sprintf("PreviewOverlayInput%d", p.Channel), nil
+	}
+	return "", fmt.Errorf("Invalid overlay mode:%s", p.Mode)
+}
+
+func (p OverlayPI) Execute(ctx context.Context, s *stateStore) error {
+	name, err := p.function()
 	if err != nil {
-		return false, err
+		return err
+	}
+	params := make(map[string]string)
+	if p.Mode != overlayOut {
+		params["Input"] = p.Input
+	}
+	return s.Function(ctx, p.Target(), name, params)
+}
+
+// Target vMix connection of this action
+func (p OverlayPI) Target() vmixTarget {
+	return vmixTarget{Connection: p.Connection, Host: p.Host, Port: p.Port}
+}
+
+// UpdateTally 選択したinputがオーバーレイチャンネルで出力に乗っている場合はオーバーレイの色、それ以外はvMixのタリーと同じ色になる
+// プレビューにだけ出しているチャンネルはオンエアではないのでプレビューの色
+func (p OverlayPI) UpdateTally(st *vmixState) (tallyKind, error) {
+	input, ok := st.findInput(p.Input)
+	if !ok {
+		return tallyOff, fmt.Errorf("No input found")
 	}
-	// 以下コードだとpanicが起きそう
-	// vc.Inputs.Input[vc.Preview-1].Key == p.Input, nil
-	for _, input := range vc.Inputs.Input {
-		// 一致するinputがあればtrueを返す
-		if input.Key != p.Input {
+	tally := tallyKindOf(st.tallyOf(input.Number))
+	if overlay, ok := st.findOverlay(p.Channel); ok && overlay.Input == input.Number {
+		if !overlay.Preview {
+			return tallyOnOverlay, nil
+		}
+		if tally != tallyOnProgram {
+			return tallyOnPreview, nil
+		}
+	}
+	return tally, nil
+}
+
+const (
+	// transition modes
+	transitionEffect = "Effect" // send the effect function with Duration
+	transitionButton = "Button" // fire Transition1-4 configured in vMix
+)
+
+// transitionEffects vMix transition functions selectable in Effect mode
+var transitionEffects = map[string]struct{}{
+	"Fade": {}, "Merge": {}, "Zoom": {}, "Wipe": {}, "Slide": {}, "Fly": {},
+	"CrossZoom": {}, "FlyRotate": {}, "Cube": {}, "CubeZoom": {},
+	"VerticalWipe": {}, "VerticalSlide": {}, "WipeReverse": {}, "SlideReverse": {},
+	"VerticalWipeReverse": {}, "VerticalSlideReverse": {}, "BarnDoor": {}, "RollerDoor": {},
+	"Stinger1": {}, "Stinger2": {},
+}
+
+// TransitionPI Property Inspector info for Transition
+type TransitionPI struct {
+	Version    int    `json:"version,string"` // settingsVersion
+	Connection string `json:"connection"`     // Connection profile ID
+	Host       string `json:"host"`
+	Port       int    `json:"port,string"`
+	Input      string `json:"input"`
+	UseInput   bool   `json:"use_input"` // falseの場合Previewのinputでトランジションする
+	Mix        string `json:"mix"`
+	Mode       string `json:"mode"`
+	Effect     string `json:"effect"`
+	Duration   int    `json:"duration,string"` // ms
+	Button     int    `json:"button,string"`   // Transition1-4
+}
+
+func (p *TransitionPI) Initialize() {
+	p.Version = settingsVersion
+	p.Host = "localhost"
+	p.Port = 8088
+	p.Input = "0"
+	p.UseInput = false
+	p.Mix = ""
+	p.Mode = transitionEffect
+	p.Effect = "Fade"
+	p.Duration = 500
+	p.Button = 1
+}
+
+func (p TransitionPI) Execute(ctx context.Context, s *stateStore) error {
+	params := make(map[string]string)
+	if p.Mix != "" {
+		params["Mix"] = p.Mix
+	}
+
+	switch p.Mode {
+	case transitionButton:
+		if p.Button < 1 || p.Button > 4 {
+			return fmt.Errorf("Invalid transition button:%d", p.Button)
+		}
+		return s.Function(ctx, p.Target(), fmt.Sprintf("Transition%d", p.Button), params)
+	case transitionEffect:
+		if _, ok := transitionEffects[p.Effect]; !ok {
+			return fmt.Errorf("Invalid transition effect:%s", p.Effect)
+		}
+		if p.UseInput {
+			params["Input"] = p.Input
+		}
+		if p.Duration > 0 {
+			params["Duration"] = strconv.Itoa(p.Duration)
+		}
+		return s.Function(ctx, p.Target(), p.Effect, params)
+	}
+	return fmt.Errorf("Invalid transition mode:%s", p.Mode)
+}
+
+// Target vMix connection of this action
+func (p TransitionPI) Target() vmixTarget {
+	return vmixTarget{Connection: p.Connection, Host: p.Host, Port: p.Port}
+}
+
+const (
+	// outputs
+	outputRecording   = "Recording"
+	outputStreaming   = "Streaming"
+	outputExternal    = "External"
+	outputMultiCorder = "MultiCorder"
+)
+
+// OutputPI Property Inspector info for Recording/Streaming/External/MultiCorder toggle
+type OutputPI struct {
+	Version     int    `json:"version,string"` // settingsVersion
+	Connection  string `json:"connection"`     // Connection profile ID
+	Host        string `json:"host"`
+	Port        int    `json:"port,string"`
+	Output      string `json:"output"`
+	Channel     string `json:"channel"`             // Streaming only. "" for all, "0"-"2" for each channel
+	Confirm     string `json:"confirm"`             // "", "hold" or "double"
+	ConfirmTime int    `json:"confirm_time,string"` // ms
+}
+
+func (p *OutputPI) Initialize() {
+	p.Version = settingsVersion
+	p.Host = "localhost"
+	p.Port = 8088
+	p.Output = outputRecording
+	p.Channel = ""
+	p.Confirm = confirmNone
+	p.ConfirmTime = int(defaultConfirmTime / time.Millisecond)
+}
+
+func (p OutputPI) Execute(ctx context.Context, s *stateStore) error {
+	params := make(map[string]string)
+	switch p.Output {
+	case outputRecording:
+		return s.Function(ctx, p.Target(), "StartStopRecording", params)
+	case outputStreaming:
+		if p.Channel != "" {
+			params["Value"] = p.Channel
+		}
+		return s.Function(ctx, p.Target(), "StartStopStreaming", params)
+	case outputExternal:
+		return s.Function(ctx, p.Target(), "StartStopExternal", params)
+	case outputMultiCorder:
+		return s.Function(ctx, p.Target(), "StartStopMultiCorder", params)
+	}
+	return fmt.Errorf("Invalid output:%s", p.Output)
+}
+
+// Target vMix connection of this action
+func (p OutputPI) Target() vmixTarget {
+	return vmixTarget{Connection: p.Connection, Host: p.Host, Port: p.Port}
+}
+
+// UpdateState 出力中の場合trueが帰る
+func (p OutputPI) UpdateState(st *vmixState) (bool, error) {
+	switch p.Output {
+	case outputRecording:
+		return st.Recording, nil
+	case outputStreaming:
+		return st.Streaming.channel(p.Channel)
+	case outputExternal:
+		return st.External, nil
+	case outputMultiCorder:
+		return st.MultiCorder, nil
+	}
+	return false, fmt.Errorf("Invalid output:%s", p.Output)
+}
+
+// MacroPI Property Inspector info for Macro.
+// Steps one step per line: "FunctionName [Key=Value&Key=Value] [delay ms]"
+type MacroPI struct {
+	Version    int    `json:"version,string"` // settingsVersion
+	Connection string `json:"connection"`     // Connection profile ID
+	Host       string `json:"host"`
+	Port       int    `json:"port,string"`
+	Steps      string `json:"steps"`
+}
+
+// macroStep single function in a macro
+type macroStep struct {
+	Name   string
+	Params map[string]string
+	Delay  time.Duration // wait before the next step
+}
+
+func (p *MacroPI) Initialize() {
+	p.Version = settingsVersion
+	p.Host = "localhost"
+	p.Port = 8088
+	p.Steps = ""
+}
+
+// Target vMix connection of this action
+func (p MacroPI) Target() vmixTarget {
+	return vmixTarget{Connection: p.Connection, Host: p.Host, Port: p.Port}
+}
+
+// ParseSteps parse Steps. Empty lines and lines starting with # are skipped.
+func (p MacroPI) ParseSteps() ([]macroStep, error) {
+	steps := []macroStep{}
+	for i, line := range strings.Split(p.Steps, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
 			continue
 		}
-		return input.Number == vc.Active, nil
+		step := macroStep{Name: fields[0], Params: map[string]string{}}
+		for _, f := range fields[1:] {
+			// "="が無いものは待ち時間。数字でなければ打ち間違いなので実行しない
+			if !strings.Contains(f, "=") {
+				delay, err := strconv.Atoi(f)
+				if err != nil || delay < 0 {
+					return nil, fmt.Errorf("Invalid delay at line %d:%s", i+1, f)
+				}
+				step.Delay = time.Duration(delay) * time.Millisecond
+				continue
+			}
+			for _, kv := range strings.Split(f, "&") {
+				if k, _, ok := strings.Cut(kv, "="); !ok || k == "" {
+					return nil, fmt.Errorf("Invalid params at line %d:%s", i+1, kv)
+				}
+			}
+			q, err := url.ParseQuery(f)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid params at line %d:%w", i+1, err)
+			}
+			for k := range q {
+				step.Params[k] = q.Get(k)
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+const (
+	// audio modes
+	audioSet  = "Set"  // set Volume
+	audioFade = "Fade" // fade to Volume in Fade ms. Inputs only
+	audioStep = "Step" // add Step to the current volume. Negative to turn down
+	audioMute = "Mute" // toggle mute
+
+	// busMaster Bus for master audio. "" targets the input
+	busMaster = "M"
+)
+
+// AudioPI Property Inspector info for Audio volume.
+// On Stream Deck+ dials, rotation adds Step per tick, press toggles mute and touch sets Volume.
+type AudioPI struct {
+	Version    int    `json:"version,string"` // settingsVersion
+	Connection string `json:"connection"`     // Connection profile ID
+	Host       string `json:"host"`
+	Port       int    `json:"port,string"`
+	Input      string `json:"input"`
+	Bus        string `json:"bus"` // "" for Input, "M" for master, "A"-"G" for buses
+	Mode       string `json:"mode"`
+	Volume     int    `json:"volume,string"` // 0-100
+	Fade       int    `json:"fade,string"`   // ms
+	Step       int    `json:"step,string"`
+}
+
+func (p *AudioPI) Initialize() {
+	p.Version = settingsVersion
+	p.Host = "localhost"
+	p.Port = 8088
+	p.Input = "0"
+	p.Bus = ""
+	p.Mode = audioMute
+	p.Volume = 100
+	p.Fade = 500
+	p.Step = 5
+}
+
+// Execute run Mode except Step, which needs the current volume
+func (p AudioPI) Execute(ctx context.Context, s *stateStore) error {
+	switch p.Mode {
+	case audioSet:
+		return p.SetVolume(ctx, s, float64(p.Volume))
+	case audioFade:
+		if p.Bus != "" {
+			return fmt.Errorf("Fade is not supported for bus:%s", p.Bus)
+		}
+		params := make(map[string]string)
+		params["Input"] = p.Input
+		params["Value"] = fmt.Sprintf("%d,%d", p.Volume, p.Fade)
+		return s.Function(ctx, p.Target(), "SetVolumeFade", params)
+	case audioMute:
+		return p.ToggleMute(ctx, s)
+	}
+	return fmt.Errorf("Invalid audio mode:%s", p.Mode)
+}
+
+// SetVolume SetVolume, SetMasterVolume or SetBusXVolume. v is clamped to 0-100
+func (p AudioPI) SetVolume(ctx context.Context, s *stateStore, v float64) error {
+	params := make(map[string]string)
+	params["Value"] = strconv.Itoa(int(math.Round(math.Max(0, math.Min(100, v)))))
+	switch p.Bus {
+	case "":
+		params["Input"] = p.Input
+		return s.Function(ctx, p.Target(), "SetVolume", params)
+	case busMaster:
+		return s.Function(ctx, p.Target(), "SetMasterVolume", params)
+	case "A", "B", "C", "D", "E", "F", "G":
+		return s.Function(ctx, p.Target(), fmt.Sprintf("SetBus%sVolume", p.Bus), params)
+	}
+	return fmt.Errorf("Invalid bus:%s", p.Bus)
+}
+
+// ToggleMute Audio, MasterAudio or BusXAudio
+func (p AudioPI) ToggleMute(ctx context.Context, s *stateStore) error {
+	params := make(map[string]string)
+	switch p.Bus {
+	case "":
+		params["Input"] = p.Input
+		return s.Function(ctx, p.Target(), "Audio", params)
+	case busMaster:
+		return s.Function(ctx, p.Target(), "MasterAudio", params)
+	case "A", "B", "C", "D", "E", "F", "G":
+		params["Value"] = p.Bus
+		return s.Function(ctx, p.Target(), "BusXAudio", params)
+	}
+	return fmt.Errorf("Invalid bus:%s", p.Bus)
+}
+
+// Target vMix connection of this action
+func (p AudioPI) Target() vmixTarget {
+	return vmixTarget{Connection: p.Connection, Host: p.Host, Port: p.Port}
+}
+
+// UpdateLevel 現在の音量とミュート状態を返す
+func (p AudioPI) UpdateLevel(st *vmixState) (float64, bool, error) {
+	if p.Bus == "" {
+		input, ok := st.findInput(p.Input)
+		if !ok {
+			return 0, false, fmt.Errorf("No input found")
+		}
+		return input.Volume, input.Muted, nil
+	}
+	bus, ok := st.findBus(p.Bus)
+	if !ok {
+		return 0, false, fmt.Errorf("No bus found:%s", p.Bus)
 	}
-	return false, fmt.Errorf("No input found")
+	return bus.Volume, bus.Muted, nil
+}
+
+// audioRoutingFunctions functions selectable in AudioRoutingPI. AudioBus* take Bus as Value
+var audioRoutingFunctions = map[string]struct{}{
+	"Audio": {}, "AudioOn": {}, "AudioOff": {},
+	"Solo": {}, "SoloOn": {}, "SoloOff": {},
+	"AudioBus": {}, "AudioBusOn": {}, "AudioBusOff": {},
+}
+
+// AudioRoutingPI Property Inspector info for input mute/solo/bus routing
+type AudioRoutingPI struct {
+	Version    int    `json:"version,string"` // settingsVersion
+	Connection string `json:"connection"`     // Connection profile ID
+	Host       string `json:"host"`
+	Port       int    `json:"port,string"`
+	Input      string `json:"input"`
+	Function   string `json:"function"`
+	Bus        string `json:"bus"` // AudioBus* only. "M" or "A"-"G"
+}
+
+func (p *AudioRoutingPI) Initialize() {
+	p.Version = settingsVersion
+	p.Host = "localhost"
+	p.Port = 8088
+	p.Input = "0"
+	p.Function = "Audio"
+	p.Bus = "A"
 }
 
-func (p *ProgramPI) UpdateInputs() error {
-	if p.Host == "" || p.Port == 0 {
-		return nil // HostかPortがゼロ値の場合何もしない
+// isBus AudioBus, AudioBusOn or AudioBusOff
+func (p AudioRoutingPI) isBus() bool {
+	return strings.HasPrefix(p.Function, "AudioBus")
+}
+
+func (p AudioRoutingPI) Execute(ctx context.Context, s *stateStore) error {
+	if _, ok := audioRoutingFunctions[p.Function]; !ok {
+		return fmt.Errorf("Invalid audio function:%s", p.Function)
+	}
+	params := make(map[string]string)
+	params["Input"] = p.Input
+	if p.isBus() {
+		params["Value"] = p.Bus
+	}
+	return s.Function(ctx, p.Target(), p.Function, params)
+}
+
+// Target vMix connection of this action
+func (p AudioRoutingPI) Target() vmixTarget {
+	return vmixTarget{Connection: p.Connection, Host: p.Host, Port: p.Port}
+}
+
+// UpdateState 音声が出ている、ソロ、バスに送られている場合trueが帰る
+func (p AudioRoutingPI) UpdateState(st *vmixState) (bool, error) {
+	input, ok := st.findInput(p.Input)
+	if !ok {
+		return false, fmt.Errorf("No input found")
+	}
+	switch {
+	case p.isBus():
+		return input.onBus(p.Bus), nil
+	case strings.HasPrefix(p.Function, "Solo"):
+		return input.Solo, nil
 	}
-	vc, err := vmixhttp.NewClient(p.Host, p.Port)
+	return !input.Muted, nil
+}
+
+// MeterPI Property Inspector info for level meter
+type MeterPI struct {
+	Version    int    `json:"version,string"` // settingsVersion
+	Connection string `json:"connection"`     // Connection profile ID
+	Host       string `json:"host"`
+	Port       int    `json:"port,string"`
+	Input      string `json:"input"`
+	Bus        string `json:"bus"` // "" for Input, "M" for master, "A"-"G" for buses
+}
+
+func (p *MeterPI) Initialize() {
+	p.Version = settingsVersion
+	p.Host = "localhost"
+	p.Port = 8088
+	p.Input = "0"
+	p.Bus = busMaster
+}
+
+// Target vMix connection of this action
+func (p MeterPI) Target() vmixTarget {
+	return vmixTarget{Connection: p.Connection, Host: p.Host, Port: p.Port}
+}
+
+// UpdateMeter 左右のレベル(0-1)を返す
+func (p MeterPI) UpdateMeter(st *vmixState) (float64, float64, error) {
+	if p.Bus == "" {
+		input, ok := st.findInput(p.Input)
+		if !ok {
+			return 0, 0, fmt.Errorf("No input found")
+		}
+		return input.MeterF1, input.MeterF2, nil
+	}
+	bus, ok := st.findBus(p.Bus)
+	if !ok {
+		return 0, 0, fmt.Errorf("No bus found:%s", p.Bus)
+	}
+	return bus.MeterF1, bus.MeterF2, nil
+}
+
+const (
+	// T-Bar dial press modes
+	tbarComplete = "Complete" // move the T-Bar to the end
+	tbarAuto     = "Auto"     // fire Transition1-4 configured in vMix
+
+	// tbarMax SetFader range is 0-255
+	tbarMax = 255
+)
+
+// TBarPI Property Inspector info for T-Bar on Stream Deck+ dial
+type TBarPI struct {
+	Version     int    `json:"version,string"` // settingsVersion
+	Connection  string `json:"connection"`     // Connection profile ID
+	Host        string `json:"host"`
+	Port        int    `json:"port,string"`
+	Mix         string `json:"mix"`
+	Sensitivity int    `json:"sensitivity,string"` // fader steps per tick
+	Press       string `json:"press"`
+	Button      int    `json:"button,string"` // Transition1-4 for Auto
+}
+
+func (p *TBarPI) Initialize() {
+	p.Version = settingsVersion
+	p.Host = "localhost"
+	p.Port = 8088
+	p.Mix = ""
+	p.Sensitivity = 8
+	p.Press = tbarComplete
+	p.Button = 1
+}
+
+// SetFader move the T-Bar to pos(0-255)
+func (p TBarPI) SetFader(ctx context.Context, s *stateStore, pos int) error {
+	params := make(map[string]string)
+	params["Value"] = strconv.Itoa(pos)
+	if p.Mix != "" {
+		params["Mix"] = p.Mix
+	}
+	return s.Function(ctx, p.Target(), "SetFader", params)
+}
+
+// Execute dial press
+func (p TBarPI) Execute(ctx context.Context, s *stateStore) error {
+	switch p.Press {
+	case tbarComplete:
+		return p.SetFader(ctx, s, tbarMax)
+	case tbarAuto:
+		if p.Button < 1 || p.Button > 4 {
+			return fmt.Errorf("Invalid transition button:%d", p.Button)
+		}
+		params := make(map[string]string)
+		if p.Mix != "" {
+			params["Mix"] = p.Mix
+		}
+		return s.Function(ctx, p.Target(), fmt.Sprintf("Transition%d", p.Button), params)
+	}
+	return fmt.Errorf("Invalid T-Bar press:%s", p.Press)
+}
+
+// Target vMix connection of this action
+func (p TBarPI) Target() vmixTarget {
+	return vmixTarget{Connection: p.Connection, Host: p.Host, Port: p.Port}
+}
+
+const (
+	// title field kinds
+	titleText  = "Text"  // SetText
+	titleImage = "Image" // SetImage
+	titleColor = "Color" // SetColor. Value is #RRGGBB
+
+	// title value sources
+	titleStatic  = "Static"  // always Value
+	titleList    = "List"    // next line of Values on each press
+	titleCounter = "Counter" // current number + Step on each press
+)
+
+// TitlePI Property Inspector info for Title/GT field setter
+type TitlePI struct {
+	Version    int    `json:"version,string"` // settingsVersion
+	Connection string `json:"connection"`     // Connection profile ID
+	Host       string `json:"host"`
+	Port       int    `json:"port,string"`
+	Input      string `json:"input"`
+	Field      string `json:"field"`    // SelectedName or SelectedIndex
+	ByIndex    bool   `json:"by_index"` // FieldがSelectedIndexの場合true
+	Kind       string `json:"kind"`
+	Source     string `json:"source"`
+	Value      string `json:"value"`
+	Values     string `json:"values"` // one value per line
+	Step       int    `json:"step,string"`
+}
+
+func (p *TitlePI) Initialize() {
+	p.Version = settingsVersion
+	p.Host = "localhost"
+	p.Port = 8088
+	p.Input = "0"
+	p.Field = "Headline.Text"
+	p.ByIndex = false
+	p.Kind = titleText
+	p.Source = titleStatic
+	p.Value = ""
+	p.Values = ""
+	p.Step = 1
+}
+
+// fieldIndex Field as SelectedIndex
+func (p TitlePI) fieldIndex() (int, error) {
+	i, err := strconv.Atoi(p.Field)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid field index:%s", p.Field)
+	}
+	return i, nil
+}
+
+// CurrentField current field of the title. false if not found
+func (p TitlePI) CurrentField(st *vmixState) (vmixField, bool) {
+	input, ok := st.findInput(p.Input)
+	if !ok {
+		return vmixField{}, false
+	}
+	index := 0
+	if p.ByIndex {
+		var err error
+		if index, err = p.fieldIndex(); err != nil {
+			return vmixField{}, false
+		}
+	}
+	return input.findField(p.Field, index, p.ByIndex)
+}
+
+// list Values without empty lines
+func (p TitlePI) list() []string {
+	ret := []string{}
+	for _, v := range strings.Split(p.Values, "\n") {
+		if v = strings.TrimSpace(v); v != "" {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// NextValue value to send. current is the field's value, pos the last list position of this key.
+// Returns the new list position.
+func (p TitlePI) NextValue(current string, pos int) (string, int, error) {
+	switch p.Source {
+	case titleStatic:
+		return p.Value, pos, nil
+	case titleList:
+		list := p.list()
+		if len(list) == 0 {
+			return "", pos, fmt.Errorf("No values")
+		}
+		// vMix側で変更されていても今の値の次に進む
+		for i, v := range list {
+			if v == current {
+				pos = i
+				break
+			}
+		}
+		pos = (pos + 1) % len(list)
+		return list[pos], pos, nil
+	case titleCounter:
+		n, _ := strconv.Atoi(strings.TrimSpace(current)) // 数値でなければ0から
+		return strconv.Itoa(n + p.Step), pos, nil
+	}
+	return "", pos, fmt.Errorf("Invalid title source:%s", p.Source)
+}
+
+// Set send value to the field
+func (p TitlePI) Set(ctx context.Context, s *stateStore, value string) error {
+	params := make(map[string]string)
+	params["Input"] = p.Input
+	params["Value"] = value
+	if p.ByIndex {
+		params["SelectedIndex"] = p.Field
+	} else {
+		params["SelectedName"] = p.Field
+	}
+	switch p.Kind {
+	case titleText:
+		return s.Function(ctx, p.Target(), "SetText", params)
+	case titleImage:
+		return s.Function(ctx, p.Target(), "SetImage", params)
+	case titleColor:
+		return s.Function(ctx, p.Target(), "SetColor", params)
+	}
+	return fmt.Errorf("Invalid title kind:%s", p.Kind)
+}
+
+// Target vMix connection of this action
+func (p TitlePI) Target() vmixTarget {
+	return vmixTarget{Connection: p.Connection, Host: p.Host, Port: p.Port}
+}
+
+// countdownFunctions functions selectable in CountdownPI. Set and Adjust take Value
+var countdownFunctions = map[string]struct{}{
+	"StartCountdown": {}, "StopCountdown": {}, "PauseCountdown": {},
+	"SetCountdown": {}, "AdjustCountdown": {},
+}
+
+// CountdownPI Property Inspector info for title countdown
+type CountdownPI struct {
+	Version    int    `json:"version,string"` // settingsVersion
+	Connection string `json:"connection"`     // Connection profile ID
+	Host       string `json:"host"`
+	Port       int    `json:"port,string"`
+	Input      string `json:"input"`
+	Field      string `json:"field"`    // SelectedName or SelectedIndex
+	ByIndex    bool   `json:"by_index"` // FieldがSelectedIndexの場合true
+	Function   string `json:"function"`
+	Value      string `json:"value"` // SetCountdown: "00:10:00", AdjustCountdown: seconds e.g. "-10"
+}
+
+func (p *CountdownPI) Initialize() {
+	p.Version = settingsVersion
+	p.Host = "localhost"
+	p.Port = 8088
+	p.Input = "0"
+	p.Field = "Time.Text"
+	p.ByIndex = false
+	p.Function = "StartCountdown"
+	p.Value = ""
+}
+
+func (p CountdownPI) Execute(ctx context.Context, s *stateStore) error {
+	if _, ok := countdownFunctions[p.Function]; !ok {
+		return fmt.Errorf("Invalid countdown function:%s", p.Function)
+	}
+	params := make(map[string]string)
+	params["Input"] = p.Input
+	if p.ByIndex {
+		params["SelectedIndex"] = p.Field
+	} else {
+		params["SelectedName"] = p.Field
+	}
+	switch p.Function {
+	case "SetCountdown", "AdjustCountdown":
+		params["Value"] = p.Value
+	}
+	return s.Function(ctx, p.Target(), p.Function, params)
+}
+
+// Target vMix connection of this action
+func (p CountdownPI) Target() vmixTarget {
+	return vmixTarget{Connection: p.Connection, Host: p.Host, Port: p.Port}
+}
+
+// UpdateRemaining 残り時間(カウントダウンのフィールドのテキスト)を返す
+func (p CountdownPI) UpdateRemaining(st *vmixState) (string, error) {
+	f, ok := TitlePI{Input: p.Input, Field: p.Field, ByIndex: p.ByIndex}.CurrentField(st)
+	if !ok {
+		return "", fmt.Errorf("No countdown field found:%s", p.Field)
+	}
+	return f.Value, nil
+}
+
+// replayFunctions functions selectable in ReplayPI and the range of Number. 0 if Number is not used.
+// ReplaySelectEvents and ReplayCamera are sent as ReplaySelectEvents1-20 and ReplayCamera1-8.
+var replayFunctions = map[string]int{
+	"ReplayMarkIn": 0, "ReplayMarkOut": 0,
+	"ReplayPlay": 0, "ReplayPause": 0, "ReplayPlayLastEvent": 0,
+	"ReplaySelectEvents": 20, "ReplayCamera": 8,
+	"ReplaySetSpeed": 0, "ReplayChangeSpeed": 0,
+	"ReplayLive": 0, "ReplayRecorded": 0, "ReplayLiveToggle": 0,
+	"ReplayStartStopRecording": 0,
+}
+
+// ReplayPI Property Inspector info for instant replay
+type ReplayPI struct {
+	Version    int    `json:"version,string"` // settingsVersion
+	Connection string `json:"connection"`     // Connection profile ID
+	Host       string `json:"host"`
+	Port       int    `json:"port,string"`
+	Function   string `json:"function"`
+	Number     int    `json:"number,string"` // ReplaySelectEvents: 1-20, ReplayCamera: 1-8
+	Value      string `json:"value"`         // ReplaySetSpeed: 0-1, ReplayChangeSpeed: -1-1
+}
+
+func (p *ReplayPI) Initialize() {
+	p.Version = settingsVersion
+	p.Host = "localhost"
+	p.Port = 8088
+	p.Function = "ReplayMarkIn"
+	p.Number = 1
+	p.Value = ""
+}
+
+// functionName vMix function name with Number
+func (p ReplayPI) functionName() (string, error) {
+	limit, ok := replayFunctions[p.Function]
+	if !ok {
+		return "", fmt.Errorf("Invalid replay function:%s", p.Function)
+	}
+	if limit == 0 {
+		return p.Function, nil
+	}
+	if p.Number < 1 || p.Number > limit {
+		return "", fmt.Errorf("Invalid number for %s:%d", p.Function, p.Number)
+	}
+	return p.Function + strconv.Itoa(p.Number), nil
+}
+
+func (p ReplayPI) Execute(ctx context.Context, s *stateStore) error {
+	name, err := p.functionName()
 	if err != nil {
 		return err
 	}
-	// スライスをリセットして更新
-	p.Inputs = make([]input, 0, len(vc.Inputs.Input))
-	for _, i := range vc.Inputs.Input {
-		p.Inputs = append(p.Inputs, input{
-			Name:   i.Name,
-			Key:    i.Key,
-			Number: int(i.Number),
-		})
+	params := make(map[string]string)
+	switch p.Function {
+	case "ReplaySetSpeed", "ReplayChangeSpeed":
+		params["Value"] = p.Value
+	}
+	return s.Function(ctx, p.Target(), name, params)
+}
+
+// Target vMix connection of this action
+func (p ReplayPI) Target() vmixTarget {
+	return vmixTarget{Connection: p.Connection, Host: p.Host, Port: p.Port}
+}
+
+// UpdateState key state and title of the replay key.
+// title is "" except for live and speed keys so the user's title is kept.
+func (p ReplayPI) UpdateState(st *vmixState) (bool, string, error) {
+	r, ok := st.findReplay()
+	if !ok {
+		return false, "", fmt.Errorf("No replay input found")
+	}
+	switch p.Function {
+	case "ReplayMarkIn", "ReplayMarkOut", "ReplayStartStopRecording":
+		return r.Recording, "", nil
+	case "ReplaySelectEvents":
+		return r.Events == p.Number, "", nil
+	case "ReplayCamera":
+		return r.CameraA == p.Number, "", nil
+	case "ReplayLive", "ReplayRecorded", "ReplayLiveToggle":
+		if r.Live {
+			return p.Function != "ReplayRecorded", "LIVE", nil
+		}
+		return p.Function == "ReplayRecorded", "REPLAY", nil
+	case "ReplaySetSpeed", "ReplayChangeSpeed":
+		return !r.Live, fmt.Sprintf("%.0f%%", r.Speed*100), nil
 	}
-	return nil
+	// ReplayPlay, ReplayPause, ReplayPlayLastEvent
+	return !r.Live, "", nil
 }
@@ -0,0 +1,199 @@
+package stdvmix
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSteps(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		steps string
+		want  []macroStep
+		err   bool
+	}{
+		{
+			name:  "empty",
+			steps: "",
+			want:  []macroStep{},
+		},
+		{
+			name:  "comments and blank lines are skipped",
+			steps: "# intro\n\n  \nCut",
+			want:  []macroStep{{Name: "Cut", Params: map[string]string{}}},
+		},
+		{
+			name:  "params and delay",
+			steps: "PreviewInput Input=2 500\nFade Input=2&Duration=1000",
+			want: []macroStep{
+				{Name: "PreviewInput", Params: map[string]string{"Input": "2"}, Delay: 500 * time.Millisecond},
+				{Name: "Fade", Params: map[string]string{"Input": "2", "Duration": "1000"}},
+			},
+		},
+		{
+			name:  "delay before params",
+			steps: "Cut 1000 Input=1",
+			want:  []macroStep{{Name: "Cut", Params: map[string]string{"Input": "1"}, Delay: time.Second}},
+		},
+		{
+			name:  "escaped value",
+			steps: "SetText Input=Title%201&SelectedName=Headline.Text&Value=a%26b+c",
+			want: []macroStep{{Name: "SetText", Params: map[string]string{
+				"Input": "Title 1", "SelectedName": "Headline.Text", "Value": "a&b c",
+			}}},
+		},
+		{
+			name:  "empty value",
+			steps: "SetText Input=1&Value=",
+			want:  []macroStep{{Name: "SetText", Params: map[string]string{"Input": "1", "Value": ""}}},
+		},
+		{name: "token without =", steps: "Cut Input", err: true},
+		{name: "param without =", steps: "Fade Input=1&Duration", err: true},
+		{name: "empty key", steps: "Cut =1", err: true},
+		{name: "negative delay", steps: "Cut -100", err: true},
+		{name: "bad escape", steps: "Cut Input=%zz", err: true},
+		{name: "error on a later line", steps: "Cut\nFade 1s", err: true},
+	} {
+		got, err := MacroPI{Steps: tc.steps}.ParseSteps()
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: no error, got %+v", tc.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestTitleNextValue(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		pi      TitlePI
+		current string
+		pos     int
+		want    string
+		wantPos int
+		err     bool
+	}{
+		{name: "static", pi: TitlePI{Source: titleStatic, Value: "LIVE"}, current: "x", pos: 3, want: "LIVE", wantPos: 3},
+		{name: "list from the start", pi: TitlePI{Source: titleList, Values: "A\nB\nC"}, current: "", pos: -1, want: "A", wantPos: 0},
+		{name: "list next", pi: TitlePI{Source: titleList, Values: "A\nB\nC"}, current: "A", pos: 0, want: "B", wantPos: 1},
+		{name: "list wraps", pi: TitlePI{Source: titleList, Values: "A\nB\nC"}, current: "C", pos: 2, want: "A", wantPos: 0},
+		{name: "list follows a change in vMix", pi: TitlePI{Source: titleList, Values: "A\nB\nC"}, current: "B", pos: 0, want: "C", wantPos: 2},
+		{name: "list skips blank lines", pi: TitlePI{Source: titleList, Values: "A\n\n  \nB\n"}, current: "A", pos: 0, want: "B", wantPos: 1},
+		{name: "empty list", pi: TitlePI{Source: titleList, Values: "\n "}, err: true},
+		{name: "counter", pi: TitlePI{Source: titleCounter, Step: 1}, current: "9", want: "10"},
+		{name: "counter down", pi: TitlePI{Source: titleCounter, Step: -2}, current: " 1 ", want: "-1"},
+		{name: "counter from text", pi: TitlePI{Source: titleCounter, Step: 5}, current: "Round", want: "5"},
+		{name: "unknown source", pi: TitlePI{Source: "Random"}, err: true},
+	} {
+		got, pos, err := tc.pi.NextValue(tc.current, tc.pos)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: no error, got %q", tc.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got != tc.want || pos != tc.wantPos {
+			t.Errorf("%s: got %q at %d, want %q at %d", tc.name, got, pos, tc.want, tc.wantPos)
+		}
+	}
+}
+
+func TestReplayFunctionName(t *testing.T) {
+	for _, tc := range []struct {
+		function string
+		number   int
+		want     string
+		err      bool
+	}{
+		{function: "ReplayMarkIn", want: "ReplayMarkIn"},
+		{function: "ReplayPlay", number: 3, want: "ReplayPlay"}, // Numberは使わない
+		{function: "ReplaySelectEvents", number: 1, want: "ReplaySelectEvents1"},
+		{function: "ReplaySelectEvents", number: 20, want: "ReplaySelectEvents20"},
+		{function: "ReplaySelectEvents", number: 21, err: true},
+		{function: "ReplayCamera", number: 8, want: "ReplayCamera8"},
+		{function: "ReplayCamera", number: 0, err: true},
+		{function: "ReplayCamera", number: 9, err: true},
+		{function: "Cut", err: true},
+	} {
+		got, err := ReplayPI{Function: tc.function, Number: tc.number}.functionName()
+		if tc.err {
+			if err == nil {
+				t.Errorf("functionName(%s, %d): no error, got %s", tc.function, tc.number, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("functionName(%s, %d): %v", tc.function, tc.number, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("functionName(%s, %d) = %s, want %s", tc.function, tc.number, got, tc.want)
+		}
+	}
+}
+
+func TestReplayUpdateState(t *testing.T) {
+	const replayXML = `<vmix><inputs>
+<input key="k1" number="1" title="Camera 1"/>
+<input key="k2" number="2" title="Replay"><replay live="%s" recording="True" events="3" cameraA="2" speed="0.5"/></input>
+</inputs></vmix>`
+	parse := func(live string) *vmixState {
+		st, err := parseState([]byte(fmt.Sprintf(replayXML, live)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return st
+	}
+	live, recorded := parse("True"), parse("False")
+
+	for _, tc := range []struct {
+		function string
+		number   int
+		st       *vmixState
+		on       bool
+		title    string
+	}{
+		{function: "ReplayMarkIn", st: live, on: true},
+		{function: "ReplayStartStopRecording", st: recorded, on: true},
+		{function: "ReplaySelectEvents", number: 3, st: live, on: true},
+		{function: "ReplaySelectEvents", number: 4, st: live, on: false},
+		{function: "ReplayCamera", number: 2, st: live, on: true},
+		{function: "ReplayCamera", number: 1, st: live, on: false},
+		{function: "ReplayLive", st: live, on: true, title: "LIVE"},
+		{function: "ReplayLive", st: recorded, on: false, title: "REPLAY"},
+		{function: "ReplayRecorded", st: live, on: false, title: "LIVE"},
+		{function: "ReplayRecorded", st: recorded, on: true, title: "REPLAY"},
+		{function: "ReplayLiveToggle", st: live, on: true, title: "LIVE"},
+		{function: "ReplaySetSpeed", st: recorded, on: true, title: "50%"},
+		{function: "ReplayChangeSpeed", st: live, on: false, title: "50%"},
+		{function: "ReplayPlay", st: recorded, on: true},
+		{function: "ReplayPause", st: live, on: false},
+	} {
+		on, title, err := ReplayPI{Function: tc.function, Number: tc.number}.UpdateState(tc.st)
+		if err != nil {
+			t.Errorf("%s %d: %v", tc.function, tc.number, err)
+			continue
+		}
+		if on != tc.on || title != tc.title {
+			t.Errorf("%s %d live=%v: got %v %q, want %v %q", tc.function, tc.number, tc.st == live, on, title, tc.on, tc.title)
+		}
+	}
+
+	if _, _, err := (ReplayPI{Function: "ReplayPlay"}).UpdateState(parseTestState(t)); err == nil {
+		t.Error("UpdateState without replay input: no error")
+	}
+}
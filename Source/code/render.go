@@ -0,0 +1,274 @@
+package stdvmix
+
+import (
+	"context"
+	"sync"
+
+	"github.com/FlowingSPDG/streamdeck"
+	sdcontext "github.com/FlowingSPDG/streamdeck/context"
+)
+
+// keyVisual last image/title/state sent to one key
+type keyVisual struct {
+	image    string
+	imageSet bool
+	title    string
+	titleSet bool
+	state    int
+	stateSet bool
+}
+
+// renderCache last rendered visual per context.
+// Update runs on every TALLY/XML, so only transitions are sent to the Stream Deck.
+// Update is called from several goroutines (TCP sessions, websocket handlers, timers), so each key is locked
+// across the compare and the send. Otherwise the cache could hold a newer visual than the one shown on the key.
+type renderCache struct {
+	mu   sync.Mutex
+	keys map[string]*renderedKey
+}
+
+// renderedKey visual of one key and the lock held while sending it
+type renderedKey struct {
+	mu     sync.Mutex
+	visual keyVisual
+}
+
+// key get or create the entry of ctxStr
+func (r *renderCache) key(ctxStr string) *renderedKey {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.keys == nil {
+		r.keys = map[string]*renderedKey{}
+	}
+	k, ok := r.keys[ctxStr]
+	if !ok {
+		k = &renderedKey{}
+		r.keys[ctxStr] = k
+	}
+	return k
+}
+
+// change apply fn to the visual of ctxStr and call send if it changed.
+// The key stays locked until send returns.
+func (r *renderCache) change(ctxStr string, fn func(v *keyVisual) bool, send func()) {
+	k := r.key(ctxStr)
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if !fn(&k.visual) {
+		return
+	}
+	send()
+}
+
+// forget drop the cache of ctxStr so everything is sent again
+func (r *renderCache) forget(ctxStr string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.keys, ctxStr)
+}
+
+// forgetAll drop the cache of every context
+func (r *renderCache) forgetAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys = nil
+}
+
+// setImage SetImage unless the key already shows img. "" restores the manifest image.
+func (s *StdVmix) setImage(ctx context.Context, img string) {
+	s.rendered.change(sdcontext.Context(ctx), func(v *keyVisual) bool {
+		if v.imageSet && v.image == img {
+			return false
+		}
+		v.image, v.imageSet = img, true
+		return true
+	}, func() {
+		s.c.SetImage(ctx, img, streamdeck.HardwareAndSoftware)
+	})
+}
+
+// setTitle SetTitle unless the key already shows title. "" restores the user's title.
+func (s *StdVmix) setTitle(ctx context.Context, title string) {
+	s.rendered.change(sdcontext.Context(ctx), func(v *keyVisual) bool {
+		if v.titleSet && v.title == title {
+			return false
+		}
+		v.title, v.titleSet = title, true
+		return true
+	}, func() {
+		s.c.SetTitle(ctx, title, streamdeck.HardwareAndSoftware)
+	})
+}
+
+// setState SetState unless the key is already in state
+func (s *StdVmix) setState(ctx context.Context, state int) {
+	s.rendered.change(sdcontext.Context(ctx), func(v *keyVisual) bool {
+		if v.stateSet && v.state == state {
+			return false
+		}
+		v.state, v.stateSet = state, true
+		return true
+	}, func() {
+		s.c.SetState(ctx, state)
+	})
+}
+
+// labelTallyPI preview and program settings. Both show the input label and tally in the same way
+type labelTallyPI interface {
+	UpdateLabel(st *vmixState) (string, error)
+	UpdateTally(st *vmixState) (tallyKind, error)
+}
+
+// renderLabelTally label title and tally image of preview and program keys. name is used in logs
+func (s *StdVmix) renderLabelTally(ctx context.Context, st *vmixState, name string, p labelTallyPI, label, tally bool, colors TallyColors) {
+	if label {
+		l, err := p.UpdateLabel(st)
+		if err != nil {
+			s.logKeyError(ctx, "Failed to get label for "+name, err)
+			return
+		}
+		s.setTitle(ctx, l)
+	} else {
+		s.setTitle(ctx, "")
+	}
+
+	if !tally {
+		if label {
+			s.setImage(ctx, s.tally.LabelImage(colors, tallyOff))
+		} else {
+			s.setImage(ctx, "")
+		}
+		return
+	}
+	kind, err := p.UpdateTally(st)
+	if err != nil {
+		s.logKeyError(ctx, "Failed to get tally for "+name, err)
+		return
+	}
+	if label {
+		s.setImage(ctx, s.tally.LabelImage(colors, kind))
+		return
+	}
+	s.setImage(ctx, s.tally.Image(colors, kind))
+}
+
+// renderOnOff on/off state of toggle keys
+func (s *StdVmix) renderOnOff(ctx context.Context, on bool) {
+	if on {
+		s.setState(ctx, stateOn)
+		return
+	}
+	s.setState(ctx, stateOff)
+}
+
+func (p SendFunctionPI) render(ctx context.Context, s *StdVmix, st *vmixState) {
+	s.sendInputs(ctx, st, p.Input)
+}
+
+func (p PreviewPI) render(ctx context.Context, s *StdVmix, st *vmixState) {
+	s.sendMixes(ctx, st, p.Input, p.Mix)
+	s.renderLabelTally(ctx, st, "preview", p, p.Label, p.Tally, p.TallyColors)
+}
+
+func (p ProgramPI) render(ctx context.Context, s *StdVmix, st *vmixState) {
+	s.sendMixes(ctx, st, p.Input, p.Mix)
+	s.renderLabelTally(ctx, st, "program", p, p.Label, p.Tally, p.TallyColors)
+}
+
+func (p OverlayPI) render(ctx context.Context, s *StdVmix, st *vmixState) {
+	s.sendInputs(ctx, st, p.Input)
+
+	if !p.Tally {
+		s.setImage(ctx, "") // タリーを切ったときに点灯したまま残らないように戻す
+		return
+	}
+	tally, err := p.UpdateTally(st)
+	if err != nil {
+		s.logKeyError(ctx, "Failed to get tally for overlay", err)
+		return
+	}
+	s.setImage(ctx, s.tally.Image(p.TallyColors, tally))
+}
+
+func (p TransitionPI) render(ctx context.Context, s *StdVmix, st *vmixState) {
+	s.sendInputs(ctx, st, p.Input)
+}
+
+func (p OutputPI) render(ctx context.Context, s *StdVmix, st *vmixState) {
+	on, err := p.UpdateState(st)
+	if err != nil {
+		s.logKeyError(ctx, "Failed to get state for output", err)
+		return
+	}
+	s.renderOnOff(ctx, on)
+}
+
+// render マクロのキーはオフライン表示だけ
+func (p MacroPI) render(ctx context.Context, s *StdVmix, st *vmixState) {}
+
+func (p AudioPI) render(ctx context.Context, s *StdVmix, st *vmixState) {
+	s.sendInputs(ctx, st, p.Input)
+
+	volume, muted, err := p.UpdateLevel(st)
+	if err != nil {
+		s.logKeyError(ctx, "Failed to get level for audio", err)
+		return
+	}
+	// キーとタッチストリップの両方に表示する
+	volume = s.currentVolume(sdcontext.Context(ctx), volume)
+	s.setImage(ctx, mustImage(levelImage(volume, muted)))
+	s.setTitle(ctx, levelTitle(volume, muted))
+}
+
+func (p AudioRoutingPI) render(ctx context.Context, s *StdVmix, st *vmixState) {
+	s.sendInputs(ctx, st, p.Input)
+
+	on, err := p.UpdateState(st)
+	if err != nil {
+		s.logKeyError(ctx, "Failed to get state for audio routing", err)
+		return
+	}
+	s.renderOnOff(ctx, on)
+}
+
+// render メーターの描画はmeterLoopで行う
+func (p MeterPI) render(ctx context.Context, s *StdVmix, st *vmixState) {
+	s.sendInputs(ctx, st, p.Input)
+}
+
+func (p TBarPI) render(ctx context.Context, s *StdVmix, st *vmixState) {
+	s.renderFader(ctx, s.faderPosition(sdcontext.Context(ctx)))
+}
+
+func (p TitlePI) render(ctx context.Context, s *StdVmix, st *vmixState) {
+	s.sendInputs(ctx, st, p.Input)
+
+	// テキストのフィールドのみ今の値をタイトルに出す
+	if p.Kind != titleText {
+		return
+	}
+	if f, ok := p.CurrentField(st); ok {
+		s.setTitle(ctx, f.Value)
+	}
+}
+
+func (p CountdownPI) render(ctx context.Context, s *StdVmix, st *vmixState) {
+	s.sendInputs(ctx, st, p.Input)
+
+	remaining, err := p.UpdateRemaining(st)
+	if err != nil {
+		s.logKeyError(ctx, "Failed to get remaining time for countdown", err)
+		return
+	}
+	s.setTitle(ctx, remaining)
+}
+
+func (p ReplayPI) render(ctx context.Context, s *StdVmix, st *vmixState) {
+	on, title, err := p.UpdateState(st)
+	if err != nil {
+		s.logKeyError(ctx, "Failed to get state for replay", err)
+		return
+	}
+	s.setTitle(ctx, title)
+	s.renderOnOff(ctx, on)
+}
@@ -0,0 +1,38 @@
+package stdvmix
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestRenderCacheSendsInCacheOrder(t *testing.T) {
+	var r renderCache
+	var mu sync.Mutex
+	shown := ""
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		img := strconv.Itoa(i % 3)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.change("key", func(v *keyVisual) bool {
+				if v.imageSet && v.image == img {
+					return false
+				}
+				v.image, v.imageSet = img, true
+				return true
+			}, func() {
+				mu.Lock()
+				shown = img
+				mu.Unlock()
+			})
+		}()
+	}
+	wg.Wait()
+
+	if cached := r.key("key").visual.image; cached != shown {
+		t.Errorf("cache = %q, key shows %q", cached, shown)
+	}
+}
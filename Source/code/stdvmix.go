@@ -2,10 +2,10 @@ package stdvmix
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"sync"
-	"time"
 
 	"github.com/FlowingSPDG/streamdeck"
 	sdcontext "github.com/FlowingSPDG/streamdeck/context"
@@ -23,13 +23,45 @@ const (
 
 	// ActionProgram Take input action Name
 	ActionProgram = "dev.flowingspdg.vmix.program"
+
+	// ActionOverlay Overlay channel action Name
+	ActionOverlay = "dev.flowingspdg.vmix.overlay"
+
+	// ActionTransition Transition action Name
+	ActionTransition = "dev.flowingspdg.vmix.transition"
+
+	// ActionOutput Recording/Streaming/External/MultiCorder action Name
+	ActionOutput = "dev.flowingspdg.vmix.output"
+
+	// ActionMacro Multi-step function macro action Name
+	ActionMacro = "dev.flowingspdg.vmix.macro"
+
+	// ActionAudio Audio volume/mute action Name. Supports Stream Deck+ dials
+	ActionAudio = "dev.flowingspdg.vmix.audio"
+
+	// ActionAudioRouting Input mute/solo/bus routing action Name
+	ActionAudioRouting = "dev.flowingspdg.vmix.audiorouting"
+
+	// ActionMeter Audio level meter action Name
+	ActionMeter = "dev.flowingspdg.vmix.meter"
+
+	// ActionTBar T-Bar action Name. Stream Deck+ dial only
+	ActionTBar = "dev.flowingspdg.vmix.tbar"
+
+	// ActionTitle Title/GT field setter action Name
+	ActionTitle = "dev.flowingspdg.vmix.title"
+
+	// ActionCountdown Title countdown action Name
+	ActionCountdown = "dev.flowingspdg.vmix.countdown"
+
+	// ActionReplay Instant replay action Name
+	ActionReplay = "dev.flowingspdg.vmix.replay"
 )
 
 const (
-	// tally color
-	tallyInactive string = "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAEgAAABICAYAAABV7bNHAAAC6HpUWHRSYXcgcHJvZmlsZSB0eXBlIGV4aWYAAHja7Zddch0pDIXfWUWWgCSExHJofqqyg1l+DnTf9r12ZpLUzMtUXagGLOiD0KfGdhh/fZ/hGwqVzCGpeS45R5RUUuGKgcezlN1STLvdJT3m6NUe7gmGSdDL+aPVa32FXT9euHWOV3vwa4b9EqJbeBdZO69xf3YSdj7tlC6hMs5BLm7Prh6XULsWbleu5+l493HDi8EQpa7YSJiHkMTdptMDWQ9JRZ/QshSso21R8XCaLjEE5OV4jz7G5wC9BPkxCp+jf48+BZ/rZZdPscxXjDD46QTpJ7vc2/DzxnJ7xK8TJg+pr0Ges/uc4zxdTRkRzVdG7WDTQwYLD4Rc9msZ1fAoxrZrQfVYYwPyHls8UBsVYlCZgRJ1qjRp7L5Rg4uJBxt65gZQy+ZiXLjJ4pRWpckmRbo4YDUeQQRmvn2hvW/Z+zVy7NwJS5kgRnjlb2v4p8k/qWHOtkJE0e9YwS9eeQ03FrnVYhWA0Ly46Q7wo17441P+IFVBUHeYHQes8TglDqWP3JLNWbBO0Z+fEAXrlwBChL0VziDtE8VMopQpGrMRIY4OQBWesyQ+QIBUucNJTiK4j4yd1954x2ivZeXMy4y7CSBUshjYFKmAlZIifyw5cqiqaFLVrKYetGjNklPWnLPldclVE0umls3MrVh18eTq2c3di9fCRXAHasnFipdSauVQsVGFVsX6CsvBhxzp0CMfdvhRjtqQPi01bblZ81Za7dyl45rouVv3XnodFAZuipGGjjxs+CijTuTalJmmzjxt+iyz3tQuql/qH1CjixpvUmud3dRgDWYPCVrXiS5mIMaJQNwWASQ0L2bRKSVe5BazWBgfhTKc1MUmdFrEgDANYp10s/sg91vcgvpvceNfkQsL3X9BLgDdV24/odbX77m2iZ1f4YppFHx903plD3hiRPNv+7fQW+gt9BZ6C72F3kL/fyGZ+OMB/xSGH33UnVw3YM8qAAAAZ3pUWHRSYXcgcHJvZmlsZSB0eXBlIGlwdGMAAHjaPUxBDoAwDLr3FT5hg6rrc5bOgzcP/j/iYoSUNoVg53WnLRO+GZvDw0dx8QdQs4C7zk6waCqGtkvBmG7KPVjFzlVFfKOhwPdiswf3FBdySWckggAAAYRpQ0NQSUNDIHByb2ZpbGUAAHicfZE9SMNAHMVf00pFKh3sIKKQoTpZEBXpqFUoQoVQK7TqYHLpFzRpSFJcHAXXgoMfi1UHF2ddHVwFQfADxM3NSdFFSvxfWmgR48FxP97de9y9A4RGhWlWYALQdNtMJxNiNrcqBl8RwAgExBGWmWXMSVIKnuPrHj6+3sV4lve5P0e/mrcY4BOJZ5lh2sQbxDObtsF5nzjCSrJKfE48btIFiR+5rrT4jXPRZYFnRsxMep44QiwWu1jpYlYyNeJp4qiq6ZQvZFusct7irFVqrH1P/sJQXl9Z5jrNYSSxiCVIEKGghjIqsBGjVSfFQpr2Ex7+IdcvkUshVxmMHAuoQoPs+sH/4He3VmFqspUUSgA9L47zMQoEd4Fm3XG+jx2neQL4n4ErveOvNoD4J+n1jhY9AsLbwMV1R1P2gMsdYPDJkE3Zlfw0hUIBeD+jb8oBA7dA31qrt/Y+Th+ADHWVugEODoGxImWve7y7t7u3f8+0+/sBda5yqHjnlIUAAA9ZaVRYdFhNTDpjb20uYWRvYmUueG1wAAAAAAA8P3hwYWNrZXQgYmVnaW49Iu+7vyIgaWQ9Ilc1TTBNcENlaGlIenJlU3pOVGN6a2M5ZCI/Pgo8eDp4bXBtZXRhIHhtbG5zOng9ImFkb2JlOm5zOm1ldGEvIiB4OnhtcHRrPSJYTVAgQ29yZSA0LjQuMC1FeGl2MiI+CiA8cmRmOlJERiB4bWxuczpyZGY9Imh0dHA6Ly93d3cudzMub3JnLzE5OTkvMDIvMjItcmRmLXN5bnRheC1ucyMiPgogIDxyZGY6RGVzY3JpcHRpb24gcmRmOmFib3V0PSIiCiAgICB4bWxuczppcHRjRXh0PSJodHRwOi8vaXB0Yy5vcmcvc3RkL0lwdGM0eG1wRXh0LzIwMDgtMDItMjkvIgogICAgeG1sbnM6eG1wTU09Imh0dHA6Ly9ucy5hZG9iZS5jb20veGFwLzEuMC9tbS8iCiAgICB4bWxuczpzdEV2dD0iaHR0cDovL25zLmFkb2JlLmNvbS94YXAvMS4wL3NUeXBlL1Jlc291cmNlRXZlbnQjIgogICAgeG1sbnM6cGx1cz0iaHR0cDovL25zLnVzZXBsdXMub3JnL2xkZi94bXAvMS4wLyIKICAgIHhtbG5zOkdJTVA9Imh0dHA6Ly93d3cuZ2ltcC5vcmcveG1wLyIKICAgIHhtbG5zOmRjPSJodHRwOi8vcHVybC5vcmcvZGMvZWxlbWVudHMvMS4xLyIKICAgIHhtbG5zOnhtcD0iaHR0cDovL25zLmFkb2JlLmNvbS94YXAvMS4wLyIKICAgeG1wTU06RG9jdW1lbnRJRD0iZ2ltcDpkb2NpZDpnaW1wOjViY2U0YWU3LTI5OTMtNDI0ZS04MDgwLWEzMzJjMTc2OGM4OCIKICAgeG1wTU06SW5zdGFuY2VJRD0ieG1wLmlpZDo5Y2JiMTk3MS1mMmFiLTRlMDQtYjdmNy1hODAxZmRiMGE0NzMiCiAgIHhtcE1NOk9yaWdpbmFsRG9jdW1lbnRJRD0ieG1wLmRpZDpjZWM4Nzc0OC04MmVjLTRiOWYtOTg1MC1lNmJlNDY0MTJiZTYiCiAgIEdJTVA6QVBJPSIyLjAiCiAgIEdJTVA6UGxhdGZvcm09Ik1hYyBPUyIKICAgR0lNUDpUaW1lU3RhbXA9IjE2MTk2NjUxMTA5ODcyNjQiCiAgIEdJTVA6VmVyc2lvbj0iMi4xMC4xNCIKICAgZGM6Rm9ybWF0PSJpbWFnZS9wbmciCiAgIHhtcDpDcmVhdG9yVG9vbD0iR0lNUCAyLjEwIj4KICAgPGlwdGNFeHQ6TG9jYXRpb25DcmVhdGVkPgogICAgPHJkZjpCYWcvPgogICA8L2lwdGNFeHQ6TG9jYXRpb25DcmVhdGVkPgogICA8aXB0Y0V4dDpMb2NhdGlvblNob3duPgogICAgPHJkZjpCYWcvPgogICA8L2lwdGNFeHQ6TG9jYXRpb25TaG93bj4KICAgPGlwdGNFeHQ6QXJ0d29ya09yT2JqZWN0PgogICAgPHJkZjpCYWcvPgogICA8L2lwdGNFeHQ6QXJ0d29ya09yT2JqZWN0PgogICA8aXB0Y0V4dDpSZWdpc3RyeUlkPgogICAgPHJkZjpCYWcvPgogICA8L2lwdGNFeHQ6UmVnaXN0cnlJZD4KICAgPHhtcE1NOkhpc3Rvcnk+CiAgICA8cmRmOlNlcT4KICAgICA8cmRmOmxpCiAgICAgIHN0RXZ0OmFjdGlvbj0ic2F2ZWQiCiAgICAgIHN0RXZ0OmNoYW5nZWQ9Ii8iCiAgICAgIHN0RXZ0Omluc3RhbmNlSUQ9InhtcC5paWQ6MDNhZmM1ZDMtZGI4ZC00NjA4LTliN2UtNDQwNzFmMzY3YWUxIgogICAgICBzdEV2dDpzb2Z0d2FyZUFnZW50PSJHaW1wIDIuMTAgKE1hYyBPUykiCiAgICAgIHN0RXZ0OndoZW49IjIwMjEtMDQtMjlUMTE6NTg6MzArMDk6MDAiLz4KICAgIDwvcmRmOlNlcT4KICAgPC94bXBNTTpIaXN0b3J5PgogICA8cGx1czpJbWFnZVN1cHBsaWVyPgogICAgPHJkZjpTZXEvPgogICA8L3BsdXM6SW1hZ2VTdXBwbGllcj4KICAgPHBsdXM6SW1hZ2VDcmVhdG9yPgogICAgPHJkZjpTZXEvPgogICA8L3BsdXM6SW1hZ2VDcmVhdG9yPgogICA8cGx1czpDb3B5cmlnaHRPd25lcj4KICAgIDxyZGY6U2VxLz4KICAgPC9wbHVzOkNvcHlyaWdodE93bmVyPgogICA8cGx1czpMaWNlbnNvcj4KICAgIDxyZGY6U2VxLz4KICAgPC9wbHVzOkxpY2Vuc29yPgogIDwvcmRmOkRlc2NyaXB0aW9uPgogPC9yZGY6UkRGPgo8L3g6eG1wbWV0YT4KICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgIAogICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgCiAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAKICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgIAogICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgCiAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAKICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgIAogICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgCiAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAKICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgIAogICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgCiAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAKICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgIAogICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgCiAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAKICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgIAogICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgCiAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAKICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgIAogICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgCiAgICAgICAgICAgICAgICAgICAgICAgICAgIAo8P3hwYWNrZXQgZW5kPSJ3Ij8+7MRfwQAAAAZiS0dEAP8A/wD/oL2nkwAAAAlwSFlzAAALEwAACxMBAJqcGAAAAAd0SU1FB+UEHQI6HgGMPmcAAABySURBVHja7dAxEQAwCASwUuUY/zsUsDMkElJJ+rH6CgQJEiRIkCBBghAkSJAgQYIECUKQIEGCBAkSJAhBggQJEiRIkCBBCBIkSJAgQYIEIUiQIEGCBAkShCBBggQJEiRIkCAECRIkSJAgQYIQJEiQoCsG1+IEBwGJzGQAAAAASUVORK5CYII="
-	tallyPreview  string = "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAEgAAABICAYAAABV7bNHAAAC83pUWHRSYXcgcHJvZmlsZSB0eXBlIGV4aWYAAHja7ZdftuMmDMbfWUWXgCSExHIwf86ZHXT5/cCOb3LvTDsz7UMfAseGCPwh9JNJEsaf32b4A4VKTiGpeS45R5RUUuGKjsezlH2nmPZ9l/QYo1d7uAcYJkEr50er1/wKu348cOscr/bg1wj7JUS38C6yVl79/uwk7HzaKV1CZZydXNyeXT0uoXZN3K5c19P27u2GF4MhSl2xkDAPIYn7nk4PZF0kFW3CnaVgHm2LigU0JH6JISAv23u0MT4H6CXIj174HP279yn4XC+7fIplvmKEzncHSD/Z5V6GnxeW2yN+HTB9SH0N8pzd5xzn7mrKiGi+MmoHmx4ymHgg5LIfy6iGS9G3XQuqxxobkPfY4oHaqBCDygyUqFOlSWO3jRpcTDzY0DI3gFo2F+PCTRantCpNNinSxcGv8QgiMPPtC+11y16vkWPlTpjKBDHCIz+s4e8Gf6WGOdsKEUW/YwW/eOU13Fjk1h2zAITmxU13gB/1wh+f8gepCoK6w+zYYI3HKXEofeSWbM6CeYr2fIUoWL8EECKsrXAGaZ8oZhKlTNGYjQhxdACq8Jwl8QECpModTnISyRyMndfaeMZoz2XlzMuMswkgVLIY2BSpgJWSIn8sOXKoqmhS1aymHrRozZJT1pyz5XXIVRNLppbNzK1YdfHk6tnN3YvXwkVwBmrJxYqXUmrlULFQhVbF/ArLwYcc6dAjH3b4UY7akD4tNW25WfNWWu3cpeOY6Llb9156HRQGToqRho48bPgoo07k2pSZps48bfoss97ULqpf6i9Qo4sab1Jrnt3UYA1mDwlax4kuZiDGiUDcFgEkNC9m0SklXuQWs1gYL4UynNTFJnRaxIAwDWKddLP7IPdT3IL6T3HjfyIXFrr/glwAuq/cvkOtr++5tomdb+GKaRS8fT1X9hr4mEjfiZd0fcS322+24d8KvIXeQm+ht9Bb6C30Fvp/CAl+QOCPbPgLErueUnLkblgAAABmelRYdFJhdyBwcm9maWxlIHR5cGUgaXB0YwAAeNo9SkEOgDAMuvcVPmGFarfnLJsHbx78fySLEVIgBbvuZ9i2EIexBqLFLCH+AHwUMBU7waJzTHlIwbbaofaki527MWXOqsH3YtoL9uAXbmjIu/EAAAGEaUNDUElDQyBwcm9maWxlAAB4nH2RPUjDQBzFX9NKRSod7CCikKE6WRAV6ahVKEKFUCu06mBy6Rc0aUhSXBwF14KDH4tVBxdnXR1cBUHwA8TNzUnRRUr8X1poEePBcT/e3XvcvQOERoVpVmAC0HTbTCcTYja3KgZfEcAIBMQRlpllzElSCp7j6x4+vt7FeJb3uT9Hv5q3GOATiWeZYdrEG8Qzm7bBeZ84wkqySnxOPG7SBYkfua60+I1z0WWBZ0bMTHqeOEIsFrtY6WJWMjXiaeKoqumUL2RbrHLe4qxVaqx9T/7CUF5fWeY6zWEksYglSBChoIYyKrARo1UnxUKa9hMe/iHXL5FLIVcZjBwLqEKD7PrB/+B3t1ZharKVFEoAPS+O8zEKBHeBZt1xvo8dp3kC+J+BK73jrzaA+Cfp9Y4WPQLC28DFdUdT9oDLHWDwyZBN2ZX8NIVCAXg/o2/KAQO3QN9aq7f2Pk4fgAx1lboBDg6BsSJlr3u8u7e7t3/PtPv7AXWucqh455SFAAAPWWlUWHRYTUw6Y29tLmFkb2JlLnhtcAAAAAAAPD94cGFja2V0IGJlZ2luPSLvu78iIGlkPSJXNU0wTXBDZWhpSHpyZVN6TlRjemtjOWQiPz4KPHg6eG1wbWV0YSB4bWxuczp4PSJhZG9iZTpuczptZXRhLyIgeDp4bXB0az0iWE1QIENvcmUgNC40LjAtRXhpdjIiPgogPHJkZjpSREYgeG1sbnM6cmRmPSJodHRwOi8vd3d3LnczLm9yZy8xOTk5LzAyLzIyLXJkZi1zeW50YXgtbnMjIj4KICA8cmRmOkRlc2NyaXB0aW9uIHJkZjphYm91dD0iIgogICAgeG1sbnM6aXB0Y0V4dD0iaHR0cDovL2lwdGMub3JnL3N0ZC9JcHRjNHhtcEV4dC8yMDA4LTAyLTI5LyIKICAgIHhtbG5zOnhtcE1NPSJodHRwOi8vbnMuYWRvYmUuY29tL3hhcC8xLjAvbW0vIgogICAgeG1sbnM6c3RFdnQ9Imh0dHA6Ly9ucy5hZG9iZS5jb20veGFwLzEuMC9zVHlwZS9SZXNvdXJjZUV2ZW50IyIKICAgIHhtbG5zOnBsdXM9Imh0dHA6Ly9ucy51c2VwbHVzLm9yZy9sZGYveG1wLzEuMC8iCiAgICB4bWxuczpHSU1QPSJodHRwOi8vd3d3LmdpbXAub3JnL3htcC8iCiAgICB4bWxuczpkYz0iaHR0cDovL3B1cmwub3JnL2RjL2VsZW1lbnRzLzEuMS8iCiAgICB4bWxuczp4bXA9Imh0dHA6Ly9ucy5hZG9iZS5jb20veGFwLzEuMC8iCiAgIHhtcE1NOkRvY3VtZW50SUQ9ImdpbXA6ZG9jaWQ6Z2ltcDo3ZjI1NjAzOC0xOTkwLTQ5Y2MtOTVlMi1jNzI3NDBjYzYxNTAiCiAgIHhtcE1NOkluc3RhbmNlSUQ9InhtcC5paWQ6NDk4N2VkNDktMTZhMC00NjA4LWE4NzItYzNiN2ZmMmY0ZDlhIgogICB4bXBNTTpPcmlnaW5hbERvY3VtZW50SUQ9InhtcC5kaWQ6YjIwOWVhZGMtZjFmNC00MDQxLWE5NzMtYTkwZGFhYTdhOTUzIgogICBHSU1QOkFQST0iMi4wIgogICBHSU1QOlBsYXRmb3JtPSJNYWMgT1MiCiAgIEdJTVA6VGltZVN0YW1wPSIxNjE5NjY1MDQxMDUyOTAxIgogICBHSU1QOlZlcnNpb249IjIuMTAuMTQiCiAgIGRjOkZvcm1hdD0iaW1hZ2UvcG5nIgogICB4bXA6Q3JlYXRvclRvb2w9IkdJTVAgMi4xMCI+CiAgIDxpcHRjRXh0OkxvY2F0aW9uQ3JlYXRlZD4KICAgIDxyZGY6QmFnLz4KICAgPC9pcHRjRXh0OkxvY2F0aW9uQ3JlYXRlZD4KICAgPGlwdGNFeHQ6TG9jYXRpb25TaG93bj4KICAgIDxyZGY6QmFnLz4KICAgPC9pcHRjRXh0OkxvY2F0aW9uU2hvd24+CiAgIDxpcHRjRXh0OkFydHdvcmtPck9iamVjdD4KICAgIDxyZGY6QmFnLz4KICAgPC9pcHRjRXh0OkFydHdvcmtPck9iamVjdD4KICAgPGlwdGNFeHQ6UmVnaXN0cnlJZD4KICAgIDxyZGY6QmFnLz4KICAgPC9pcHRjRXh0OlJlZ2lzdHJ5SWQ+CiAgIDx4bXBNTTpIaXN0b3J5PgogICAgPHJkZjpTZXE+CiAgICAgPHJkZjpsaQogICAgICBzdEV2dDphY3Rpb249InNhdmVkIgogICAgICBzdEV2dDpjaGFuZ2VkPSIvIgogICAgICBzdEV2dDppbnN0YW5jZUlEPSJ4bXAuaWlkOjdjNzkzZjA3LTViNjQtNDc0ZS04Mjk3LWYzMTFlOTczMDkwYyIKICAgICAgc3RFdnQ6c29mdHdhcmVBZ2VudD0iR2ltcCAyLjEwIChNYWMgT1MpIgogICAgICBzdEV2dDp3aGVuPSIyMDIxLTA0LTI5VDExOjU3OjIxKzA5OjAwIi8+CiAgICA8L3JkZjpTZXE+CiAgIDwveG1wTU06SGlzdG9yeT4KICAgPHBsdXM6SW1hZ2VTdXBwbGllcj4KICAgIDxyZGY6U2VxLz4KICAgPC9wbHVzOkltYWdlU3VwcGxpZXI+CiAgIDxwbHVzOkltYWdlQ3JlYXRvcj4KICAgIDxyZGY6U2VxLz4KICAgPC9wbHVzOkltYWdlQ3JlYXRvcj4KICAgPHBsdXM6Q29weXJpZ2h0T3duZXI+CiAgICA8cmRmOlNlcS8+CiAgIDwvcGx1czpDb3B5cmlnaHRPd25lcj4KICAgPHBsdXM6TGljZW5zb3I+CiAgICA8cmRmOlNlcS8+CiAgIDwvcGx1czpMaWNlbnNvcj4KICA8L3JkZjpEZXNjcmlwdGlvbj4KIDwvcmRmOlJERj4KPC94OnhtcG1ldGE+CiAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAKICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgIAogICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgCiAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAKICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgIAogICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgCiAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAKICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgIAogICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgCiAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAKICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgIAogICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgCiAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAKICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgIAogICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgCiAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAKICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgIAogICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgCiAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAKICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgIAogICAgICAgICAgICAgICAgICAgICAgICAgICAKPD94cGFja2V0IGVuZD0idyI/Plfb3jAAAAAGYktHRAD/AP8A/6C9p5MAAAAJcEhZcwAACxMAAAsTAQCanBgAAAAHdElNRQflBB0CORW9c7QsAAAAcElEQVR42u3QMQEAAAgDoGn/zprA3wMiUJlMOLUCQYIECRIkSJAgBAkSJEiQIEGCECRIkCBBggQJQpAgQYIECRIkSBCCBAkSJEiQIEEIEiRIkCBBggQhSJAgQYIECRIkCEGCBAkSJEiQIAQJEiToiwUf1QKOQh77lQAAAABJRU5ErkJggg=="
-	tallyProgram  string = "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAEgAAABICAYAAABV7bNHAAABhGlDQ1BJQ0MgcHJvZmlsZQAAKJF9kT1Iw0AcxV/TSkUqHewgopChOlkQFemoVShChVArtOpgcukXNGlIUlwcBdeCgx+LVQcXZ10dXAVB8APEzc1J0UVK/F9aaBHjwXE/3t173L0DhEaFaVZgAtB020wnE2I2tyoGXxHACATEEZaZZcxJUgqe4+sePr7exXiW97k/R7+atxjgE4lnmWHaxBvEM5u2wXmfOMJKskp8Tjxu0gWJH7mutPiNc9FlgWdGzEx6njhCLBa7WOliVjI14mniqKrplC9kW6xy3uKsVWqsfU/+wlBeX1nmOs1hJLGIJUgQoaCGMiqwEaNVJ8VCmvYTHv4h1y+RSyFXGYwcC6hCg+z6wf/gd7dWYWqylRRKAD0vjvMxCgR3gWbdcb6PHad5AvifgSu94682gPgn6fWOFj0CwtvAxXVHU/aAyx1g8MmQTdmV/DSFQgF4P6NvygEDt0DfWqu39j5OH4AMdZW6AQ4OgbEiZa97vLu3u7d/z7T7+wF1rnKoxhB+yAAAAAZiS0dEAP8A/wD/oL2nkwAAAAlwSFlzAAALEwAACxMBAJqcGAAAAAd0SU1FB+UEHQI4IYXccdgAAABwSURBVHja7dAxAQAACAOgaf/OmsDfAyJQk0w4tQJBggQJEiRIkCAECRIkSJAgQYIQJEiQIEGCBAlCkCBBggQJEiRIEIIECRIkSJAgQQgSJEiQIEGCBCFIkCBBggQJEiQIQYIECRIkSJAgBAkSJOiLBSDUAo5LcSa/AAAAAElFTkSuQmCC"
+	// key states for two-state actions
+	stateOff = 0
+	stateOn  = 1
 )
 
 type input struct {
@@ -38,174 +70,391 @@ type input struct {
 	Number int    `json:"number"`
 }
 
+// mix mix for Property Inspector. Value is the Mix parameter of functions
+type mix struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// inputsPayload input list sent to the open Property Inspector. Not saved in settings.
+type inputsPayload struct {
+	Inputs []input `json:"inputs"`
+	Input  string  `json:"input"` // current selection
+}
+
+// offlinePayload sent to the open Property Inspector while vMix is offline.
+// The list is emptied and the PI keeps showing the saved selection.
+type offlinePayload struct {
+	Inputs []input `json:"inputs"`
+}
+
+// mixesPayload input and mix list sent to preview/program Property Inspector
+type mixesPayload struct {
+	inputsPayload
+	Mixes []mix  `json:"mixes"`
+	Mix   string `json:"mix"` // current selection
+}
+
 type StdVmix struct {
-	c *streamdeck.Client
+	c          *streamdeck.Client
+	store      *stateStore
+	pluginUUID string
+	globalOnce sync.Once
+
+	sendFuncContexts     sync.Map // map[string]SendFunctionPI
+	previewContexts      sync.Map // map[string]PreviewPI
+	programContexts      sync.Map // map[string]ProgramPI
+	overlayContexts      sync.Map // map[string]OverlayPI
+	transitionContexts   sync.Map // map[string]TransitionPI
+	outputContexts       sync.Map // map[string]OutputPI
+	macroContexts        sync.Map // map[string]MacroPI
+	audioContexts        sync.Map // map[string]AudioPI
+	audioRoutingContexts sync.Map // map[string]AudioRoutingPI
+	meterContexts        sync.Map // map[string]MeterPI
+	tbarContexts         sync.Map // map[string]TBarPI
+	titleContexts        sync.Map // map[string]TitlePI
+	countdownContexts    sync.Map // map[string]CountdownPI
+	replayContexts       sync.Map // map[string]ReplayPI
+
+	offline sync.Map // map[string]struct{} オフライン表示中のContext
+	armed   sync.Map // map[string]*armedKey 確認待ちのContext
+	macros  sync.Map // map[string]*runningMacro 実行中のマクロ
+	volumes sync.Map // map[string]pendingVolume キー/ダイヤルから送った音量
+	faders  sync.Map // map[string]int ダイヤルから送ったT-Barの位置
+	titles  sync.Map // map[string]int タイトルのリストの位置
+	legacy  sync.Map // map[string]struct{} 接続プロファイルの読み込み前に読んだ古い設定のContext
 
-	sendFuncContexts sync.Map // map[string]SendFunctionPI
-	previewContexts  sync.Map // map[string]PreviewPI
-	programContexts  sync.Map // map[string]ProgramPI
+	keyErrors sync.Map // map[string]string 最後にログに出した描画のエラー
+
+	inspectors sync.Map // map[string]any PIを開いているContextと最後に送ったpayload
+	rendered   renderCache
+	tally      tallyRenderer // タリー画像。グローバルの色を保持する
 }
 
 func NewStdVmix(ctx context.Context, params streamdeck.RegistrationParams) *StdVmix {
 	client := streamdeck.NewClient(ctx, params)
 	ret := &StdVmix{
-		c:                client,
-		sendFuncContexts: sync.Map{},
-		previewContexts:  sync.Map{},
-		programContexts:  sync.Map{},
+		c:                    client,
+		pluginUUID:           params.PluginUUID,
+		sendFuncContexts:     sync.Map{},
+		previewContexts:      sync.Map{},
+		programContexts:      sync.Map{},
+		overlayContexts:      sync.Map{},
+		transitionContexts:   sync.Map{},
+		outputContexts:       sync.Map{},
+		macroContexts:        sync.Map{},
+		audioContexts:        sync.Map{},
+		audioRoutingContexts: sync.Map{},
+		meterContexts:        sync.Map{},
+		tbarContexts:         sync.Map{},
+		titleContexts:        sync.Map{},
+		countdownContexts:    sync.Map{},
+		replayContexts:       sync.Map{},
 	}
 
+	client.RegisterNoActionHandler(streamdeck.DidReceiveGlobalSettings, ret.DidReceiveGlobalSettingsHandler)
+	client.RegisterNoActionHandler(streamdeck.SystemDidWakeUp, ret.SystemDidWakeUpHandler)
+
 	actionFunc := client.Action(ActionFunction)
-	actionFunc.RegisterHandler(streamdeck.WillAppear, ret.SendFuncWillAppearHandler)
-	actionFunc.RegisterHandler(streamdeck.WillDisappear, func(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
-		ret.sendFuncContexts.Delete(event.Context)
-		return nil
-	})
+	actionFunc.RegisterHandler(streamdeck.WillAppear, willAppearHandler[SendFunctionPI](ret, &ret.sendFuncContexts))
+	actionFunc.RegisterHandler(streamdeck.WillDisappear, willDisappearHandler(ret, &ret.sendFuncContexts))
 	actionFunc.RegisterHandler(streamdeck.KeyDown, ret.SendFuncKeyDownHandler)
-	actionFunc.RegisterHandler(streamdeck.DidReceiveSettings, ret.SendFuncDidReceiveSettingsHandler)
+	actionFunc.RegisterHandler(streamdeck.KeyUp, ret.SendFuncKeyUpHandler)
+	actionFunc.RegisterHandler(streamdeck.DidReceiveSettings, didReceiveSettingsHandler[SendFunctionPI](ret, &ret.sendFuncContexts))
+	actionFunc.RegisterHandler(streamdeck.PropertyInspectorDidAppear, ret.PropertyInspectorDidAppearHandler)
+	actionFunc.RegisterHandler(streamdeck.PropertyInspectorDidDisappear, ret.PropertyInspectorDidDisappearHandler)
+	actionFunc.RegisterHandler(streamdeck.SendToPlugin, ret.SendToPluginHandler)
 
 	actionPrev := client.Action(ActionPreview)
-	actionPrev.RegisterHandler(streamdeck.WillAppear, ret.PreviewWillAppearHandler)
-	actionPrev.RegisterHandler(streamdeck.WillDisappear, func(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
-		ret.previewContexts.Delete(event.Context)
-		return nil
-	})
+	actionPrev.RegisterHandler(streamdeck.WillAppear, willAppearHandler[PreviewPI](ret, &ret.previewContexts))
+	actionPrev.RegisterHandler(streamdeck.WillDisappear, willDisappearHandler(ret, &ret.previewContexts))
 	actionPrev.RegisterHandler(streamdeck.KeyDown, ret.PreviewKeyDownHandler)
-	actionPrev.RegisterHandler(streamdeck.DidReceiveSettings, ret.PreviewDidReceiveSettingsHandler)
+	actionPrev.RegisterHandler(streamdeck.DidReceiveSettings, didReceiveSettingsHandler[PreviewPI](ret, &ret.previewContexts))
+	actionPrev.RegisterHandler(streamdeck.PropertyInspectorDidAppear, ret.PropertyInspectorDidAppearHandler)
+	actionPrev.RegisterHandler(streamdeck.PropertyInspectorDidDisappear, ret.PropertyInspectorDidDisappearHandler)
+	actionPrev.RegisterHandler(streamdeck.SendToPlugin, ret.SendToPluginHandler)
 
 	actionProgram := client.Action(ActionProgram)
-	actionProgram.RegisterHandler(streamdeck.WillAppear, ret.ProgramWillAppearHandler)
-	actionProgram.RegisterHandler(streamdeck.WillDisappear, func(ctx context.Context, client *streamdeck.Client, event streamdeck.Event) error {
-		ret.programContexts.Delete(event.Context)
-		return nil
-	})
+	actionProgram.RegisterHandler(streamdeck.WillAppear, willAppearHandler[ProgramPI](ret, &ret.programContexts))
+	actionProgram.RegisterHandler(streamdeck.WillDisappear, willDisappearHandler(ret, &ret.programContexts))
 	actionProgram.RegisterHandler(streamdeck.KeyDown, ret.ProgramKeyDownHandler)
-	actionProgram.RegisterHandler(streamdeck.DidReceiveSettings, ret.ProgramDidReceiveSettingsHandler)
+	actionProgram.RegisterHandler(streamdeck.DidReceiveSettings, didReceiveSettingsHandler[ProgramPI](ret, &ret.programContexts))
+	actionProgram.RegisterHandler(streamdeck.PropertyInspectorDidAppear, ret.PropertyInspectorDidAppearHandler)
+	actionProgram.RegisterHandler(streamdeck.PropertyInspectorDidDisappear, ret.PropertyInspectorDidDisappearHandler)
+	actionProgram.RegisterHandler(streamdeck.SendToPlugin, ret.SendToPluginHandler)
+
+	actionOverlay := client.Action(ActionOverlay)
+	actionOverlay.RegisterHandler(streamdeck.WillAppear, willAppearHandler[OverlayPI](ret, &ret.overlayContexts))
+	actionOverlay.RegisterHandler(streamdeck.WillDisappear, willDisappearHandler(ret, &ret.overlayContexts))
+	actionOverlay.RegisterHandler(streamdeck.KeyDown, ret.OverlayKeyDownHandler)
+	actionOverlay.RegisterHandler(streamdeck.DidReceiveSettings, didReceiveSettingsHandler[OverlayPI](ret, &ret.overlayContexts))
+	actionOverlay.RegisterHandler(streamdeck.PropertyInspectorDidAppear, ret.PropertyInspectorDidAppearHandler)
+	actionOverlay.RegisterHandler(streamdeck.PropertyInspectorDidDisappear, ret.PropertyInspectorDidDisappearHandler)
+	actionOverlay.RegisterHandler(streamdeck.SendToPlugin, ret.SendToPluginHandler)
+
+	actionTransition := client.Action(ActionTransition)
+	actionTransition.RegisterHandler(streamdeck.WillAppear, willAppearHandler[TransitionPI](ret, &ret.transitionContexts))
+	actionTransition.RegisterHandler(streamdeck.WillDisappear, willDisappearHandler(ret, &ret.transitionContexts))
+	actionTransition.RegisterHandler(streamdeck.KeyDown, ret.TransitionKeyDownHandler)
+	actionTransition.RegisterHandler(streamdeck.DidReceiveSettings, didReceiveSettingsHandler[TransitionPI](ret, &ret.transitionContexts))
+	actionTransition.RegisterHandler(streamdeck.PropertyInspectorDidAppear, ret.PropertyInspectorDidAppearHandler)
+	actionTransition.RegisterHandler(streamdeck.PropertyInspectorDidDisappear, ret.PropertyInspectorDidDisappearHandler)
+	actionTransition.RegisterHandler(streamdeck.SendToPlugin, ret.SendToPluginHandler)
+
+	actionOutput := client.Action(ActionOutput)
+	actionOutput.RegisterHandler(streamdeck.WillAppear, willAppearHandler[OutputPI](ret, &ret.outputContexts))
+	actionOutput.RegisterHandler(streamdeck.WillDisappear, willDisappearHandler(ret, &ret.outputContexts))
+	actionOutput.RegisterHandler(streamdeck.KeyDown, ret.OutputKeyDownHandler)
+	actionOutput.RegisterHandler(streamdeck.KeyUp, ret.OutputKeyUpHandler)
+	actionOutput.RegisterHandler(streamdeck.DidReceiveSettings, didReceiveSettingsHandler[OutputPI](ret, &ret.outputContexts))
+
+	actionMacro := client.Action(ActionMacro)
+	actionMacro.RegisterHandler(streamdeck.WillAppear, willAppearHandler[MacroPI](ret, &ret.macroContexts))
+	actionMacro.RegisterHandler(streamdeck.WillDisappear, willDisappearHandler(ret, &ret.macroContexts))
+	actionMacro.RegisterHandler(streamdeck.KeyDown, ret.MacroKeyDownHandler)
+	actionMacro.RegisterHandler(streamdeck.DidReceiveSettings, didReceiveSettingsHandler[MacroPI](ret, &ret.macroContexts))
+
+	actionAudio := client.Action(ActionAudio)
+	actionAudio.RegisterHandler(streamdeck.WillAppear, willAppearHandler[AudioPI](ret, &ret.audioContexts))
+	actionAudio.RegisterHandler(streamdeck.WillDisappear, willDisappearHandler(ret, &ret.audioContexts))
+	actionAudio.RegisterHandler(streamdeck.KeyDown, ret.AudioKeyDownHandler)
+	actionAudio.RegisterHandler(eventDialRotate, ret.AudioDialRotateHandler)
+	actionAudio.RegisterHandler(eventDialPress, ret.AudioDialPressHandler)
+	actionAudio.RegisterHandler(eventTouchTap, ret.AudioTouchTapHandler)
+	actionAudio.RegisterHandler(streamdeck.DidReceiveSettings, didReceiveSettingsHandler[AudioPI](ret, &ret.audioContexts))
+	actionAudio.RegisterHandler(streamdeck.PropertyInspectorDidAppear, ret.PropertyInspectorDidAppearHandler)
+	actionAudio.RegisterHandler(streamdeck.PropertyInspectorDidDisappear, ret.PropertyInspectorDidDisappearHandler)
+	actionAudio.RegisterHandler(streamdeck.SendToPlugin, ret.SendToPluginHandler)
+
+	actionAudioRouting := client.Action(ActionAudioRouting)
+	actionAudioRouting.RegisterHandler(streamdeck.WillAppear, willAppearHandler[AudioRoutingPI](ret, &ret.audioRoutingContexts))
+	actionAudioRouting.RegisterHandler(streamdeck.WillDisappear, willDisappearHandler(ret, &ret.audioRoutingContexts))
+	actionAudioRouting.RegisterHandler(streamdeck.KeyDown, ret.AudioRoutingKeyDownHandler)
+	actionAudioRouting.RegisterHandler(streamdeck.DidReceiveSettings, didReceiveSettingsHandler[AudioRoutingPI](ret, &ret.audioRoutingContexts))
+	actionAudioRouting.RegisterHandler(streamdeck.PropertyInspectorDidAppear, ret.PropertyInspectorDidAppearHandler)
+	actionAudioRouting.RegisterHandler(streamdeck.PropertyInspectorDidDisappear, ret.PropertyInspectorDidDisappearHandler)
+	actionAudioRouting.RegisterHandler(streamdeck.SendToPlugin, ret.SendToPluginHandler)
+
+	actionMeter := client.Action(ActionMeter)
+	actionMeter.RegisterHandler(streamdeck.WillAppear, willAppearHandler[MeterPI](ret, &ret.meterContexts))
+	actionMeter.RegisterHandler(streamdeck.WillDisappear, willDisappearHandler(ret, &ret.meterContexts))
+	actionMeter.RegisterHandler(streamdeck.DidReceiveSettings, didReceiveSettingsHandler[MeterPI](ret, &ret.meterContexts))
+	actionMeter.RegisterHandler(streamdeck.PropertyInspectorDidAppear, ret.PropertyInspectorDidAppearHandler)
+	actionMeter.RegisterHandler(streamdeck.PropertyInspectorDidDisappear, ret.PropertyInspectorDidDisappearHandler)
+	actionMeter.RegisterHandler(streamdeck.SendToPlugin, ret.SendToPluginHandler)
+
+	actionTBar := client.Action(ActionTBar)
+	actionTBar.RegisterHandler(streamdeck.WillAppear, willAppearHandler[TBarPI](ret, &ret.tbarContexts))
+	actionTBar.RegisterHandler(streamdeck.WillDisappear, willDisappearHandler(ret, &ret.tbarContexts))
+	actionTBar.RegisterHandler(eventDialRotate, ret.TBarDialRotateHandler)
+	actionTBar.RegisterHandler(eventDialPress, ret.TBarDialPressHandler)
+	actionTBar.RegisterHandler(streamdeck.DidReceiveSettings, didReceiveSettingsHandler[TBarPI](ret, &ret.tbarContexts))
+
+	actionTitle := client.Action(ActionTitle)
+	actionTitle.RegisterHandler(streamdeck.WillAppear, willAppearHandler[TitlePI](ret, &ret.titleContexts))
+	actionTitle.RegisterHandler(streamdeck.WillDisappear, willDisappearHandler(ret, &ret.titleContexts))
+	actionTitle.RegisterHandler(streamdeck.KeyDown, ret.TitleKeyDownHandler)
+	actionTitle.RegisterHandler(streamdeck.DidReceiveSettings, didReceiveSettingsHandler[TitlePI](ret, &ret.titleContexts))
+	actionTitle.RegisterHandler(streamdeck.PropertyInspectorDidAppear, ret.PropertyInspectorDidAppearHandler)
+	actionTitle.RegisterHandler(streamdeck.PropertyInspectorDidDisappear, ret.PropertyInspectorDidDisappearHandler)
+	actionTitle.RegisterHandler(streamdeck.SendToPlugin, ret.SendToPluginHandler)
+
+	actionCountdown := client.Action(ActionCountdown)
+	actionCountdown.RegisterHandler(streamdeck.WillAppear, willAppearHandler[CountdownPI](ret, &ret.countdownContexts))
+	actionCountdown.RegisterHandler(streamdeck.WillDisappear, willDisappearHandler(ret, &ret.countdownContexts))
+	actionCountdown.RegisterHandler(streamdeck.KeyDown, ret.CountdownKeyDownHandler)
+	actionCountdown.RegisterHandler(streamdeck.DidReceiveSettings, didReceiveSettingsHandler[CountdownPI](ret, &ret.countdownContexts))
+	actionCountdown.RegisterHandler(streamdeck.PropertyInspectorDidAppear, ret.PropertyInspectorDidAppearHandler)
+	actionCountdown.RegisterHandler(streamdeck.PropertyInspectorDidDisappear, ret.PropertyInspectorDidDisappearHandler)
+	actionCountdown.RegisterHandler(streamdeck.SendToPlugin, ret.SendToPluginHandler)
+
+	actionReplay := client.Action(ActionReplay)
+	actionReplay.RegisterHandler(streamdeck.WillAppear, willAppearHandler[ReplayPI](ret, &ret.replayContexts))
+	actionReplay.RegisterHandler(streamdeck.WillDisappear, willDisappearHandler(ret, &ret.replayContexts))
+	actionReplay.RegisterHandler(streamdeck.KeyDown, ret.ReplayKeyDownHandler)
+	actionReplay.RegisterHandler(streamdeck.DidReceiveSettings, didReceiveSettingsHandler[ReplayPI](ret, &ret.replayContexts))
 
 	ret.c = client
+	ret.store = newStateStore(ctx, ret.Update, ret.onConnState)
+	go ret.meterLoop(ctx)
+	go ret.countdownLoop(ctx)
 
 	return ret
 }
 
-// Update inputs Contextの数だけ更新が入るので負荷が高いかもしれない
-func (s *StdVmix) Update() {
-	wg := sync.WaitGroup{}
-	s.sendFuncContexts.Range(func(key, value any) bool {
-		ctxStr := key.(string)
-		val, ok := value.(SendFunctionPI)
-		if !ok {
-			msg := fmt.Sprintf("Failed to cast value for sendfunc. Actual:%s", reflect.TypeOf(value))
-			s.c.LogMessage(msg)
-			return true
-		}
-		wg.Add(1)
-		defer wg.Done()
-		go func(ctxStr string, pi SendFunctionPI) {
-			ctx := context.Background()
-			ctx = sdcontext.WithContext(ctx, ctxStr)
-
-			// val を使ってinputを更新
-			if err := pi.UpdateInputs(); err != nil {
-				// アクセスに失敗したときのログがうるさいので、errorによってログに出すか分岐したい
-				s.c.LogMessage("Failed to update inputs")
-				return
-			}
-			s.c.SetSettings(ctx, val)
-		}(ctxStr, val)
-		return true
-	})
+// onConnState 接続状態の変化をログに出す。再接続を繰り返している間は最初の失敗だけ出す
+func (s *StdVmix) onConnState(addr string, state connState, failures int, err error) {
+	if failures > 1 || (state == connConnecting && failures > 0) {
+		return
+	}
+	msg := fmt.Sprintf("vMix %s: %s", addr, state)
+	if err != nil {
+		msg = fmt.Sprintf("%s (%v)", msg, err)
+	}
+	s.c.LogMessage(msg)
+}
 
-	s.previewContexts.Range(func(key, value any) bool {
-		ctxStr := key.(string)
-		val, ok := value.(PreviewPI)
-		if !ok {
-			msg := fmt.Sprintf("Failed to cast value for preview. Actual:%s", reflect.TypeOf(value))
-			s.c.LogMessage(msg)
-			return true
-		}
-		wg.Add(1)
-		defer wg.Done()
-		go func(ctxStr string, pi PreviewPI) {
-			ctx := context.Background()
-			ctx = sdcontext.WithContext(ctx, ctxStr)
-
-			// val を使ってinputを更新
-			if err := pi.UpdateInputs(); err != nil {
-				s.c.LogMessage("Failed to update inputs")
-				return
-			}
-			s.c.SetSettings(ctx, pi)
+// logKeyError 描画のエラーをログに出す。同じContextで同じエラーが続く間は最初の一回だけ出す
+func (s *StdVmix) logKeyError(ctx context.Context, msg string, err error) {
+	ctxStr := sdcontext.Context(ctx)
+	msg = fmt.Sprintf("%s:%v", msg, err)
+	if prev, ok := s.keyErrors.Load(ctxStr); ok && prev == msg {
+		return
+	}
+	s.keyErrors.Store(ctxStr, msg)
+	s.c.LogMessage(msg)
+}
 
-			if !pi.Tally {
-				return
-			}
-			prev, err := pi.UpdateTally()
-			if err != nil {
-				s.c.LogMessage("Failed to get tally for preview")
-				return
-			}
-			if prev {
-				s.c.SetImage(ctx, tallyPreview, streamdeck.HardwareAndSoftware)
-				return
-			}
-			s.c.SetImage(ctx, tallyInactive, streamdeck.HardwareAndSoftware)
-		}(ctxStr, val)
+// renderOffline vMixに接続できない間はキーをオフライン表示にし、復帰したら元に戻す。
+// 描画を続けてよくない場合trueが帰る
+func (s *StdVmix) renderOffline(ctx context.Context, err error) bool {
+	ctxStr := sdcontext.Context(ctx)
+	if errors.Is(err, errNotFetched) {
+		return true // 初回接続中
+	}
+	if err != nil {
+		s.offline.Store(ctxStr, struct{}{})
+		s.keyErrors.Delete(ctxStr) // 復帰後のエラーはまたログに出す
+		s.setImage(ctx, tallyOffline)
+		s.setTitle(ctx, offlineTitle)
+		s.sendToInspector(ctx, offlinePayload{Inputs: []input{}})
 		return true
+	}
+	if _, loaded := s.offline.LoadAndDelete(ctxStr); loaded {
+		// 空文字でマニフェストの画像とユーザーのタイトルに戻す
+		s.setImage(ctx, "")
+		s.setTitle(ctx, "")
+	}
+	return false
+}
+
+// sendInputs PIが開いている場合のみinput一覧を送る
+func (s *StdVmix) sendInputs(ctx context.Context, st *vmixState, selected string) {
+	s.sendToInspector(ctx, inputsPayload{Inputs: st.inputList(), Input: selected})
+}
+
+// sendMixes input一覧とmix一覧を送る。プレビュー、プログラム用
+func (s *StdVmix) sendMixes(ctx context.Context, st *vmixState, selected, mix string) {
+	s.sendToInspector(ctx, mixesPayload{
+		inputsPayload: inputsPayload{Inputs: st.inputList(), Input: selected},
+		Mixes:         st.mixList(),
+		Mix:           mix,
 	})
+}
+
+// sendToInspector PIが開いている場合のみpayloadを送る。前回から変化がなければ送らない
+func (s *StdVmix) sendToInspector(ctx context.Context, payload any) {
+	ctxStr := sdcontext.Context(ctx)
+	v, ok := s.inspectors.Load(ctxStr)
+	if !ok {
+		return
+	}
+	if reflect.DeepEqual(v, payload) {
+		return
+	}
+	s.inspectors.Store(ctxStr, payload)
+	s.c.SendToPropertyInspector(ctx, payload)
+}
+
+// targeter action settings that refer to a vMix
+type targeter interface {
+	Target() vmixTarget
+}
+
+// keyRenderer action settings that draw their key from a vMix snapshot. Update calls render for every bound context
+type keyRenderer interface {
+	targeter
+	render(ctx context.Context, s *StdVmix, st *vmixState)
+}
 
-	s.programContexts.Range(func(key, value any) bool {
-		ctxStr := key.(string)
-		val, ok := value.(ProgramPI)
-		if !ok {
-			msg := fmt.Sprintf("Failed to cast value for program. Actual:%s", reflect.TypeOf(value))
-			s.c.LogMessage(msg)
+// contexts Context maps of every action
+func (s *StdVmix) contexts() []*sync.Map {
+	return []*sync.Map{
+		&s.sendFuncContexts,
+		&s.previewContexts,
+		&s.programContexts,
+		&s.overlayContexts,
+		&s.transitionContexts,
+		&s.outputContexts,
+		&s.macroContexts,
+		&s.audioContexts,
+		&s.audioRoutingContexts,
+		&s.meterContexts,
+		&s.tbarContexts,
+		&s.titleContexts,
+		&s.countdownContexts,
+		&s.replayContexts,
+	}
+}
+
+// endpoints 全Contextが参照しているvMixを重複なしで集める
+func (s *StdVmix) endpoints() map[string]endpoint {
+	ret := map[string]endpoint{}
+	for _, contexts := range s.contexts() {
+		contexts.Range(func(_, value any) bool {
+			if pi, ok := value.(targeter); ok {
+				if ep, ok := s.store.Resolve(pi.Target()); ok {
+					ret[ep.Addr()] = ep
+				}
+			}
 			return true
+		})
+	}
+	return ret
+}
+
+// sync 参照されているvMixとのセッションを張り直し、全Contextを描画する
+func (s *StdVmix) sync() {
+	s.requestGlobalSettings()
+	endpoints := s.endpoints()
+	s.store.Sync(endpoints)
+	for addr := range endpoints {
+		s.Update(addr)
+	}
+}
+
+// requestGlobalSettings 接続プロファイルを一度だけ取得する。
+// 登録前には送れないので最初のイベントで呼ぶ
+func (s *StdVmix) requestGlobalSettings() {
+	s.globalOnce.Do(func() {
+		ctx := sdcontext.WithContext(context.Background(), s.pluginUUID)
+		if err := s.c.GetGlobalSettings(ctx); err != nil {
+			s.c.LogMessage(fmt.Sprintf("Failed to get global settings:%v", err))
 		}
-		wg.Add(1)
-		defer wg.Done()
-		go func(ctxStr string, pi ProgramPI) {
-			ctx := context.Background()
-			ctx = sdcontext.WithContext(ctx, ctxStr)
-
-			// pi を使ってinputを更新
-			if err := pi.UpdateInputs(); err != nil {
-				s.c.LogMessage("Failed to update inputs")
-				return
-			}
-			s.c.SetSettings(ctx, pi)
+	})
+}
+
+// boundTo actionのTargetがaddrのvMixを指している場合trueが帰る
+func (s *StdVmix) boundTo(t vmixTarget, addr string) bool {
+	ep, ok := s.store.Resolve(t)
+	return ok && ep.Addr() == addr
+}
 
-			if !pi.Tally {
-				return
+// Update addrのスナップショットをそのvMixを参照している全Contextに配る
+func (s *StdVmix) Update(addr string) {
+	st, err := s.store.Get(addr)
+	for _, contexts := range s.contexts() {
+		contexts.Range(func(key, value any) bool {
+			ctxStr := key.(string)
+			pi, ok := value.(keyRenderer)
+			if !ok {
+				msg := fmt.Sprintf("Failed to cast value for rendering. Actual:%s", reflect.TypeOf(value))
+				s.c.LogMessage(msg)
+				return true
 			}
-			pgm, err := pi.UpdateTally()
-			if err != nil {
-				s.c.LogMessage("Failed to get tally for preview")
-				return
+			if !s.boundTo(pi.Target(), addr) {
+				return true
 			}
-			if pgm {
-				s.c.SetImage(ctx, tallyProgram, streamdeck.HardwareAndSoftware)
-				return
+			ctx := sdcontext.WithContext(context.Background(), ctxStr)
+			if s.renderOffline(ctx, err) {
+				return true // 接続エラーはonConnStateで一度だけログに出す
 			}
-			s.c.SetImage(ctx, tallyInactive, streamdeck.HardwareAndSoftware)
-		}(ctxStr, val)
-		return true
-	})
-
-	wg.Wait()
-	return
+			pi.render(ctx, s, st)
+			return true
+		})
+	}
 }
 
 func (s *StdVmix) Run(ctx context.Context) error {
-	go func() {
-		for {
-			time.Sleep(time.Second / 5) // 0.2s
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				s.Update()
-			}
-		}
-	}()
 	return s.c.Run()
 }
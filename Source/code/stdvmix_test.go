@@ -0,0 +1,260 @@
+package stdvmix
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/FlowingSPDG/streamdeck"
+	"github.com/gorilla/websocket"
+)
+
+// fakeStreamDeck Stream Deck software side of the plugin websocket
+type fakeStreamDeck struct {
+	server *httptest.Server
+	conns  chan *websocket.Conn
+}
+
+func newFakeStreamDeck(t *testing.T) *fakeStreamDeck {
+	t.Helper()
+	f := &fakeStreamDeck{conns: make(chan *websocket.Conn, 1)}
+	upgrader := websocket.Upgrader{}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Failed to upgrade:%v", err)
+			return
+		}
+		f.conns <- c
+	}))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// port port to pass with -port
+func (f *fakeStreamDeck) port(t *testing.T) int {
+	t.Helper()
+	_, port, err := net.SplitHostPort(f.server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := json.Number(port).Int64()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int(p)
+}
+
+// waitEvent read events from the plugin until name arrives
+func waitEvent(t *testing.T, c *websocket.Conn, name string) streamdeck.Event {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(time.Second * 5))
+	for {
+		event := streamdeck.Event{}
+		if err := c.ReadJSON(&event); err != nil {
+			t.Fatalf("No %s event:%v", name, err)
+		}
+		if event.Event == name {
+			return event
+		}
+	}
+}
+
+// startPlugin run the plugin against sd and return its websocket after registration
+func startPlugin(ctx context.Context, t *testing.T, sd *fakeStreamDeck, params streamdeck.RegistrationParams) *websocket.Conn {
+	t.Helper()
+	s := NewStdVmix(ctx, params)
+	go s.Run(ctx)
+
+	var c *websocket.Conn
+	select {
+	case c = <-sd.conns:
+	case <-time.After(time.Second * 5):
+		t.Fatal("Plugin did not connect")
+	}
+	t.Cleanup(func() { c.Close() })
+	waitEvent(t, c, params.RegisterEvent)
+	return c
+}
+
+func TestGetGlobalSettingsHasPluginUUID(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sd := newFakeStreamDeck(t)
+	params := streamdeck.RegistrationParams{
+		Port:          sd.port(t),
+		PluginUUID:    "plugin-uuid",
+		RegisterEvent: "registerPlugin",
+	}
+	c := startPlugin(ctx, t, sd, params)
+
+	// 最初のイベントでグローバル設定を要求する
+	if err := c.WriteJSON(streamdeck.Event{
+		Action:  ActionPreview,
+		Event:   streamdeck.WillAppear,
+		Context: "key",
+		Payload: json.RawMessage(`{"settings":{"host":"127.0.0.1","port":"1"}}`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	event := waitEvent(t, c, streamdeck.GetGlobalSettings)
+	if event.Context != params.PluginUUID {
+		t.Errorf("getGlobalSettings context = %q, want %q", event.Context, params.PluginUUID)
+	}
+}
+
+func TestEveryActionHasTarget(t *testing.T) {
+	// targeterでないと接続先が集められず、セッションが開始されない
+	for _, pi := range []any{
+		SendFunctionPI{}, PreviewPI{}, ProgramPI{}, OverlayPI{}, TransitionPI{}, OutputPI{}, MacroPI{},
+		AudioPI{}, AudioRoutingPI{}, MeterPI{}, TBarPI{}, TitlePI{}, CountdownPI{}, ReplayPI{},
+	} {
+		if _, ok := pi.(targeter); !ok {
+			t.Errorf("%T has no Target", pi)
+		}
+		// keyRendererでないとUpdateで描画されない
+		if _, ok := pi.(keyRenderer); !ok {
+			t.Errorf("%T has no render", pi)
+		}
+	}
+}
+
+func TestInspectorGetsListAgainWhenConnected(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 接続できないvMix
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcpPort := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	sd := newFakeStreamDeck(t)
+	params := streamdeck.RegistrationParams{
+		Port:          sd.port(t),
+		PluginUUID:    "plugin-uuid",
+		RegisterEvent: "registerPlugin",
+	}
+	c := startPlugin(ctx, t, sd, params)
+
+	send := func(event streamdeck.Event) {
+		t.Helper()
+		if err := c.WriteJSON(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	send(streamdeck.Event{
+		Event:   streamdeck.DidReceiveGlobalSettings,
+		Context: params.PluginUUID,
+		Payload: json.RawMessage(`{"settings":{"connections":[{"id":"studio","host":"127.0.0.1","port":"8088","tcp_port":"` + strconv.Itoa(tcpPort) + `"}]}}`),
+	})
+	send(streamdeck.Event{
+		Action:  ActionPreview,
+		Event:   streamdeck.WillAppear,
+		Context: "key",
+		Payload: json.RawMessage(`{"settings":{"version":"2","connection":"studio","input":"k1"}}`),
+	})
+	send(streamdeck.Event{Action: ActionPreview, Event: streamdeck.PropertyInspectorDidAppear, Context: "key"})
+
+	// オフラインの間は空の一覧を送る
+	event := waitEvent(t, c, streamdeck.SendToPropertyInspector)
+	if string(event.Payload) != `{"inputs":[]}` {
+		t.Errorf("offline payload = %s", event.Payload)
+	}
+
+	// PIの登録前に送ったものは届いていないかもしれないので、接続の通知で送り直す
+	send(streamdeck.Event{
+		Action:  ActionPreview,
+		Event:   streamdeck.SendToPlugin,
+		Context: "key",
+		Payload: json.RawMessage(`{"property_inspector":"propertyInspectorConnected"}`),
+	})
+	event = waitEvent(t, c, streamdeck.SendToPropertyInspector)
+	if string(event.Payload) != `{"inputs":[]}` {
+		t.Errorf("payload after connected = %s", event.Payload)
+	}
+}
+
+// TestInspectorListsRoundTrip dynamic dropdowns of the PIs are filled from the pushed payload by id,
+// and restored from and saved to the settings by sdValueField. A mismatch silently resets the selection on the next edit.
+func TestInspectorListsRoundTrip(t *testing.T) {
+	mixes := mixesPayload{inputsPayload: inputsPayload{Inputs: []input{}}, Mixes: []mix{}}
+	inputs := inputsPayload{Inputs: []input{}}
+	for file, tc := range map[string]struct {
+		settings any
+		payload  any
+	}{
+		"preview.html":      {PreviewPI{}, mixes},
+		"program.html":      {ProgramPI{}, mixes},
+		"function.html":     {SendFunctionPI{}, inputs},
+		"overlay.html":      {OverlayPI{}, inputs},
+		"transition.html":   {TransitionPI{}, inputs},
+		"audio.html":        {AudioPI{}, inputs},
+		"audiorouting.html": {AudioRoutingPI{}, inputs},
+		"meter.html":        {MeterPI{}, inputs},
+		"title.html":        {TitlePI{}, inputs},
+		"countdown.html":    {CountdownPI{}, inputs},
+	} {
+		html, err := os.ReadFile(filepath.Join("..", "pi", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		settings, payload := jsonKeys(t, tc.settings), jsonKeys(t, tc.payload)
+		lists := sdListPattern.FindAllStringSubmatch(string(html), -1)
+		if len(lists) == 0 {
+			t.Errorf("%s: no dynamic dropdown", file)
+		}
+		for _, m := range lists {
+			id, valueField := attr(m[0], "id"), attr(m[0], "sdValueField")
+			if !payload[id] {
+				t.Errorf("%s: list %q is not in the pushed payload", file, id)
+			}
+			if !payload[valueField] {
+				t.Errorf("%s: selection %q of %q is not in the pushed payload", file, valueField, id)
+			}
+			if !settings[valueField] {
+				t.Errorf("%s: selection %q of %q is not a setting", file, valueField, id)
+			}
+		}
+	}
+}
+
+var sdListPattern = regexp.MustCompile(`<select[^>]*class="[^"]*\bsdList\b[^"]*"[^>]*>`)
+
+// attr attribute value of an HTML tag
+func attr(tag, name string) string {
+	m := regexp.MustCompile(`\b` + name + `="([^"]*)"`).FindStringSubmatch(tag)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// jsonKeys top-level keys of v encoded as JSON
+func jsonKeys(t *testing.T, v any) map[string]bool {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	ret := map[string]bool{}
+	for k := range m {
+		ret[k] = true
+	}
+	return ret
+}
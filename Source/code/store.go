@@ -0,0 +1,178 @@
+package stdvmix
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	errNotFetched   = errors.New("vMix state not fetched yet")
+	errNoConnection = errors.New("No vMix connection configured")
+)
+
+// snapshot latest state for one host
+type snapshot struct {
+	state *vmixState
+	err   error
+}
+
+// stateStore vMix state cache keyed by TCP API host:port.
+// One TCP session runs per host and its snapshot is shared by every context.
+type stateStore struct {
+	ctx      context.Context
+	onUpdate func(addr string)
+	onState  func(addr string, state connState, failures int, err error)
+
+	mu          sync.Mutex
+	sessions    map[string]storeSession
+	connections []Connection
+	loaded      bool     // global settings received at least once
+	snapshots   sync.Map // map[string]snapshot
+}
+
+func newStateStore(ctx context.Context, onUpdate func(addr string), onState func(addr string, state connState, failures int, err error)) *stateStore {
+	return &stateStore{
+		ctx:      ctx,
+		onUpdate: onUpdate,
+		onState:  onState,
+		sessions: map[string]storeSession{},
+	}
+}
+
+// storeSession running session and its stop func
+type storeSession struct {
+	*tcpSession
+	endpoint endpoint
+	cancel   context.CancelFunc
+}
+
+// SetConnections replace connection profiles from global settings
+func (s *stateStore) SetConnections(conns []Connection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connections = conns
+	s.loaded = true
+}
+
+// Connections current connection profiles. false until global settings are received.
+func (s *stateStore) Connections() ([]Connection, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections, s.loaded
+}
+
+// Resolve resolve action's target to endpoint with current connection profiles
+func (s *stateStore) Resolve(t vmixTarget) (endpoint, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return resolveTarget(s.connections, t)
+}
+
+// Sync start sessions for new hosts and stop sessions for hosts no longer used.
+// Sessions whose profile changed (e.g. credentials) are restarted.
+func (s *stateStore) Sync(endpoints map[string]endpoint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for addr, session := range s.sessions {
+		if ep, ok := endpoints[addr]; ok && ep == session.endpoint {
+			continue
+		}
+		session.cancel()
+		delete(s.sessions, addr)
+		s.snapshots.Delete(addr)
+	}
+
+	for _, ep := range endpoints {
+		s.start(ep)
+	}
+}
+
+// start run a session for ep unless it is already running. s.mu must be held.
+func (s *stateStore) start(ep endpoint) *tcpSession {
+	addr := ep.Addr()
+	if session, ok := s.sessions[addr]; ok {
+		return session.tcpSession
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	session := newTCPSession(ep, func(st *vmixState, redraw bool, err error) {
+		if ctx.Err() != nil {
+			return
+		}
+		s.snapshots.Store(addr, snapshot{state: st, err: err})
+		if redraw {
+			s.onUpdate(addr)
+		}
+	}, func(state connState, failures int, err error) {
+		if ctx.Err() != nil {
+			return
+		}
+		s.onState(addr, state, failures, err)
+	})
+	s.sessions[addr] = storeSession{tcpSession: session, endpoint: ep, cancel: cancel}
+	go session.Run(ctx)
+	return session
+}
+
+// Function send function to target through its shared session.
+// Actions that never appeared (e.g. inside a multi action) get a session on demand.
+func (s *stateStore) Function(ctx context.Context, t vmixTarget, name string, params map[string]string) error {
+	s.mu.Lock()
+	ep, ok := resolveTarget(s.connections, t)
+	if !ok {
+		s.mu.Unlock()
+		return errNoConnection
+	}
+	session := s.start(ep)
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, functionTimeout)
+	defer cancel()
+	return session.Function(ctx, name, params)
+}
+
+// Get latest snapshot for host:port
+func (s *stateStore) Get(addr string) (*vmixState, error) {
+	v, ok := s.snapshots.Load(addr)
+	if !ok {
+		return nil, errNotFetched
+	}
+	snap := v.(snapshot)
+	return snap.state, snap.err
+}
+
+// State latest snapshot of the vMix target points to
+func (s *stateStore) State(t vmixTarget) (*vmixState, error) {
+	ep, ok := s.Resolve(t)
+	if !ok {
+		return nil, errNoConnection
+	}
+	return s.Get(ep.Addr())
+}
+
+// Refresh ask target's vMix for a full XML now. Used after functions ACTS does not report, like volume changes.
+func (s *stateStore) Refresh(t vmixTarget) {
+	if session, ok := s.running(t); ok {
+		session.requestXML()
+	}
+}
+
+// RefreshMeters ask target's vMix for XML for level meters. Keys are not redrawn when it arrives.
+func (s *stateStore) RefreshMeters(t vmixTarget) {
+	if session, ok := s.running(t); ok {
+		session.requestMeterXML()
+	}
+}
+
+// running session of target if it is running
+func (s *stateStore) running(t vmixTarget) (*tcpSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ep, ok := resolveTarget(s.connections, t)
+	if !ok {
+		return nil, false
+	}
+	session, running := s.sessions[ep.Addr()]
+	return session.tcpSession, running
+}
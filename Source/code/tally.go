@@ -0,0 +1,209 @@
+package stdvmix
+
+import (
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"sync"
+
+	vmixtcp "github.com/FlowingSPDG/vmix-go/tcp"
+)
+
+const (
+	// tally styles
+	tallyFill   = "fill"   // whole key in the tally color
+	tallyBorder = "border" // tally color border around the inactive color
+	tallyDot    = "dot"    // tally color dot at the top right corner
+
+	// tallyBorderWidth border width of tallyBorder
+	tallyBorderWidth = 8
+
+	// tallyDotRadius radius of tallyDot
+	tallyDotRadius = 10
+
+	// labelInactive background of keys showing the input label. The white title is not readable on light colors
+	labelInactive = "#202020"
+)
+
+// tallyKind which tally color to draw
+type tallyKind int
+
+const (
+	tallyOff tallyKind = iota
+	tallyOnPreview
+	tallyOnProgram
+	tallyOnOverlay
+)
+
+// tallyKindOf tally color of vMix tally status
+func tallyKindOf(status vmixtcp.TallyStatus) tallyKind {
+	switch status {
+	case vmixtcp.Program:
+		return tallyOnProgram
+	case vmixtcp.Preview:
+		return tallyOnPreview
+	}
+	return tallyOff
+}
+
+// TallyColors tally colors and style.
+// Empty fields of action settings fall back to the global settings, then to defaultTallyColors.
+type TallyColors struct {
+	Preview  string `json:"tally_preview"` // "#RRGGBB" or "#RRGGBBAA"
+	Program  string `json:"tally_program"`
+	Overlay  string `json:"tally_overlay"`
+	Inactive string `json:"tally_inactive"`
+	Style    string `json:"tally_style"` // fill, border or dot
+}
+
+// defaultTallyColors same colors as the PNGs used before colors were configurable
+var defaultTallyColors = TallyColors{
+	Preview:  "#00FF00",
+	Program:  "#FF0000",
+	Overlay:  "#FF0000",
+	Inactive: "#FDFDFD80",
+	Style:    tallyFill,
+}
+
+// merge fill empty fields of c with def
+func (c TallyColors) merge(def TallyColors) TallyColors {
+	for _, f := range []struct {
+		v *string
+		d string
+	}{
+		{&c.Preview, def.Preview},
+		{&c.Program, def.Program},
+		{&c.Overlay, def.Overlay},
+		{&c.Inactive, def.Inactive},
+		{&c.Style, def.Style},
+	} {
+		if *f.v == "" {
+			*f.v = f.d
+		}
+	}
+	return c
+}
+
+// color color of kind
+func (c TallyColors) color(kind tallyKind) string {
+	switch kind {
+	case tallyOnPreview:
+		return c.Preview
+	case tallyOnProgram:
+		return c.Program
+	case tallyOnOverlay:
+		return c.Overlay
+	}
+	return c.Inactive
+}
+
+// parseColor "#RRGGBB" or "#RRGGBBAA"
+func parseColor(s string) (color.NRGBA, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "#"))
+	if err != nil || (len(b) != 3 && len(b) != 4) {
+		return color.NRGBA{}, fmt.Errorf("Invalid color:%s", s)
+	}
+	c := color.NRGBA{R: b[0], G: b[1], B: b[2], A: 0xff}
+	if len(b) == 4 {
+		c.A = b[3]
+	}
+	return c, nil
+}
+
+// tallyImage key image of kind drawn in style
+func tallyImage(style string, fg, bg color.Color, kind tallyKind) (image.Image, error) {
+	img := image.NewNRGBA(image.Rect(0, 0, keySize, keySize))
+	if kind == tallyOff {
+		draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+		return img, nil
+	}
+	switch style {
+	case tallyFill:
+		draw.Draw(img, img.Bounds(), image.NewUniform(fg), image.Point{}, draw.Src)
+	case tallyBorder:
+		draw.Draw(img, img.Bounds(), image.NewUniform(fg), image.Point{}, draw.Src)
+		inner := image.Rect(tallyBorderWidth, tallyBorderWidth, keySize-tallyBorderWidth, keySize-tallyBorderWidth)
+		draw.Draw(img, inner, image.NewUniform(bg), image.Point{}, draw.Src)
+	case tallyDot:
+		draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+		cx, cy := keySize-tallyDotRadius-4, tallyDotRadius+4
+		for y := cy - tallyDotRadius; y <= cy+tallyDotRadius; y++ {
+			for x := cx - tallyDotRadius; x <= cx+tallyDotRadius; x++ {
+				if (x-cx)*(x-cx)+(y-cy)*(y-cy) <= tallyDotRadius*tallyDotRadius {
+					img.Set(x, y, fg)
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("Invalid tally style:%s", style)
+	}
+	return img, nil
+}
+
+// tallyRenderer tally images for the global and per-action colors.
+// Encoded images are cached since Update runs on every TALLY.
+type tallyRenderer struct {
+	mu     sync.Mutex
+	global TallyColors
+	images map[tallyImageKey]string
+}
+
+// tallyImageKey cache key of tallyRenderer
+type tallyImageKey struct {
+	colors TallyColors
+	kind   tallyKind
+}
+
+// SetGlobal update the global colors
+func (r *tallyRenderer) SetGlobal(c TallyColors) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.global = c
+}
+
+// Image data URI of kind for the action colors c.
+// Invalid colors or style fall back to the defaults so a typo does not leave the key blank.
+func (r *tallyRenderer) Image(c TallyColors, kind tallyKind) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c = c.merge(r.global).merge(defaultTallyColors)
+	key := tallyImageKey{colors: c, kind: kind}
+	if img, ok := r.images[key]; ok {
+		return img
+	}
+
+	fg, err := parseColor(c.color(kind))
+	if err != nil {
+		fg, _ = parseColor(defaultTallyColors.color(kind))
+	}
+	bg, err := parseColor(c.Inactive)
+	if err != nil {
+		bg, _ = parseColor(defaultTallyColors.Inactive)
+	}
+	img, err := tallyImage(c.Style, fg, bg, kind)
+	if err != nil {
+		img, _ = tallyImage(defaultTallyColors.Style, fg, bg, kind)
+	}
+
+	if r.images == nil {
+		r.images = map[tallyImageKey]string{}
+	}
+	r.images[key] = mustImage(img)
+	return r.images[key]
+}
+
+// LabelImage Image for keys showing the input label.
+// The background stays dark and fill is drawn as a border, same as the framed label keys before colors were configurable.
+func (r *tallyRenderer) LabelImage(c TallyColors, kind tallyKind) string {
+	r.mu.Lock()
+	c = c.merge(r.global).merge(defaultTallyColors)
+	r.mu.Unlock()
+	c.Inactive = labelInactive
+	if c.Style == tallyFill {
+		c.Style = tallyBorder
+	}
+	return r.Image(c, kind)
+}
@@ -0,0 +1,23 @@
+package stdvmix
+
+import (
+	"testing"
+)
+
+func TestLabelImageKeepsDarkBackground(t *testing.T) {
+	var r tallyRenderer
+	for _, kind := range []tallyKind{tallyOff, tallyOnPreview, tallyOnProgram} {
+		fill := TallyColors{Style: tallyFill}
+		want := r.Image(TallyColors{Inactive: labelInactive, Style: tallyBorder}, kind)
+		if got := r.LabelImage(fill, kind); got != want {
+			t.Errorf("kind %d: label image is not a border on the dark background", kind)
+		}
+	}
+
+	// 背景色はユーザーの設定より読みやすさを優先する
+	dot := TallyColors{Inactive: "#FFFFFF", Style: tallyDot}
+	want := r.Image(TallyColors{Inactive: labelInactive, Style: tallyDot}, tallyOnProgram)
+	if got := r.LabelImage(dot, tallyOnProgram); got != want {
+		t.Error("dot style label image is not on the dark background")
+	}
+}
@@ -0,0 +1,45 @@
+package stdvmix
+
+import (
+	"context"
+	"fmt"
+
+	sdcontext "github.com/FlowingSPDG/streamdeck/context"
+)
+
+// vMix XML does not report the T-Bar position, so each dial keeps the position it sent.
+// vMix completes the transition at the end of the T-Bar and the next one starts from 0.
+
+// faderPosition position last sent by the dial
+func (s *StdVmix) faderPosition(ctxStr string) int {
+	if v, ok := s.faders.Load(ctxStr); ok {
+		return v.(int)
+	}
+	return 0
+}
+
+// moveFader move the T-Bar by ticks * Sensitivity
+func (s *StdVmix) moveFader(ctx context.Context, pi TBarPI, ticks int) error {
+	ctxStr := sdcontext.Context(ctx)
+	pos := s.faderPosition(ctxStr) + ticks*pi.Sensitivity
+	if pos < 0 {
+		pos = 0
+	} else if pos > tbarMax {
+		pos = tbarMax
+	}
+	if err := pi.SetFader(ctx, s.store, pos); err != nil {
+		return fmt.Errorf("Failed to set fader:%w", err)
+	}
+	if pos == tbarMax {
+		pos = 0 // トランジション完了
+	}
+	s.faders.Store(ctxStr, pos)
+	s.renderFader(ctx, pos)
+	return nil
+}
+
+// renderFader show T-Bar position on the touch strip
+func (s *StdVmix) renderFader(ctx context.Context, pos int) {
+	s.setImage(ctx, mustImage(faderImage(float64(pos)/tbarMax)))
+	s.setTitle(ctx, fmt.Sprintf("%d%%", pos*100/tbarMax))
+}
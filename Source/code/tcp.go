@@ -0,0 +1,375 @@
+package stdvmix
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	vmixtcp "github.com/FlowingSPDG/vmix-go/tcp"
+)
+
+const (
+	// vmixTCPPort vMix TCP API port
+	vmixTCPPort = 8099
+
+	// dialTimeout timeout for connecting vMix TCP API
+	dialTimeout = time.Second * 2
+
+	// resyncInterval full XML refresh. ACTS does not cover input add/remove/rename.
+	resyncInterval = time.Second * 5
+
+	// readTimeout no line from vMix for this long means vMix hung or the link is half-open.
+	// resync asks for XML every resyncInterval, so a live vMix always answers within it.
+	readTimeout = resyncInterval * 2
+
+	// functionTimeout wait for FUNCTION reply, or for the first connection of a new session
+	functionTimeout = time.Second * 3
+
+	// eventAuth AUTH command. vmixtcp does not have it.
+	eventAuth = "AUTH"
+)
+
+// tcpSession vMix TCP API session for one vMix instance.
+// Tally and activator changes are pushed by vMix (SUBSCRIBE TALLY / SUBSCRIBE ACTS) instead of being polled,
+// and every action bound to the host sends its FUNCTION over this connection.
+// vmixtcp dispatches every line on its own goroutine and reads XML with a single Read, so lines are handled here in order.
+type tcpSession struct {
+	endpoint endpoint
+	onUpdate func(st *vmixState, redraw bool, err error) // redraw is false for XML asked only for level meters
+	onState  func(state connState, failures int, err error)
+
+	mu         sync.Mutex
+	connState  connState
+	down       bool // failure already published through onUpdate
+	conn       net.Conn
+	state      *vmixState
+	tally      string
+	xmlPending bool
+	xmlMeter   bool          // the pending XML was asked only for level meters
+	connected  chan struct{} // closed while connected
+	pending    []chan error  // FUNCTION replies in the order they were sent
+}
+
+func newTCPSession(ep endpoint, onUpdate func(st *vmixState, redraw bool, err error), onState func(state connState, failures int, err error)) *tcpSession {
+	return &tcpSession{
+		endpoint:  ep,
+		onUpdate:  onUpdate,
+		onState:   onState,
+		connected: make(chan struct{}),
+	}
+}
+
+// Run keep the session connected until ctx is cancelled.
+// connecting -> online -> offline -> backoff -> connecting ...
+// A failed attempt goes straight from connecting to backoff, and the delay grows until it comes back online.
+func (t *tcpSession) Run(ctx context.Context) {
+	failures := 0
+	for {
+		t.setState(connConnecting, failures, nil)
+		d := net.Dialer{Timeout: dialTimeout}
+		conn, err := d.DialContext(ctx, "tcp", t.endpoint.Addr())
+		if err != nil {
+			err = fmt.Errorf("Failed to connect vmix... %w", err)
+		} else {
+			failures = 0
+			t.setState(connOnline, failures, nil)
+			err = t.serve(ctx, conn)
+			if ctx.Err() != nil {
+				return
+			}
+			t.setState(connOffline, failures, err)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		failures++
+		t.setState(connBackoff, failures, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoffDelay(failures)):
+		}
+	}
+}
+
+// setState record the new state and report it. The first failure is published as the snapshot error.
+func (t *tcpSession) setState(state connState, failures int, err error) {
+	t.mu.Lock()
+	t.connState = state
+	publish := false
+	switch state {
+	case connOnline:
+		t.down = false
+	case connOffline, connBackoff:
+		publish = !t.down
+		t.down = true
+	}
+	t.mu.Unlock()
+
+	t.onState(state, failures, err)
+	if publish {
+		t.onUpdate(nil, true, err)
+	}
+}
+
+// serve subscribe and read events until the connection is closed
+func (t *tcpSession) serve(ctx context.Context, conn net.Conn) error {
+	defer conn.Close()
+
+	t.mu.Lock()
+	t.conn = conn
+	t.state = nil
+	t.tally = ""
+	t.xmlPending = false
+	t.xmlMeter = false
+	close(t.connected)
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		t.conn = nil
+		t.connected = make(chan struct{})
+		for _, reply := range t.pending {
+			reply <- fmt.Errorf("vMix TCP API connection closed")
+		}
+		t.pending = nil
+		t.mu.Unlock()
+	}()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(resyncInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				conn.Close()
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				t.requestXML()
+			}
+		}
+	}()
+
+	cmds := []string{}
+	if t.endpoint.User != "" {
+		// Web Controllerのパスワードが設定されている場合、リモートからの接続は認証が必要
+		cmds = append(cmds, eventAuth+" "+t.endpoint.User+" "+t.endpoint.Password)
+	}
+	for _, cmd := range append(cmds,
+		vmixtcp.EVENT_SUBSCRIBE+" "+vmixtcp.EVENT_TALLY,
+		vmixtcp.EVENT_SUBSCRIBE+" "+vmixtcp.EVENT_ACTS,
+		vmixtcp.EVENT_TALLY,
+	) {
+		if err := t.write(cmd); err != nil {
+			return err
+		}
+	}
+	t.requestXML()
+
+	r := bufio.NewReader(conn)
+	for {
+		// XMLの本文もこの期限内に読む
+		if err := conn.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
+			return err
+		}
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if err := t.handle(r, strings.TrimSpace(line)); err != nil {
+			return err
+		}
+	}
+}
+
+// write send one command line
+func (t *tcpSession) write(cmd string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conn == nil {
+		return fmt.Errorf("vMix TCP API is not connected")
+	}
+	_, err := io.WriteString(t.conn, cmd+vmixtcp.Terminate)
+	return err
+}
+
+// requestXML ask for a full XML unless one is already on the way
+func (t *tcpSession) requestXML() {
+	t.askXML(false)
+}
+
+// requestMeterXML ask for XML for level meters. The reply only replaces the state, keys are not redrawn.
+// Skipped while FUNCTION replies are awaited so a meter XML does not hold them up; the next frame asks again.
+func (t *tcpSession) requestMeterXML() {
+	t.mu.Lock()
+	busy := len(t.pending) > 0
+	t.mu.Unlock()
+	if busy {
+		return
+	}
+	t.askXML(true)
+}
+
+func (t *tcpSession) askXML(meter bool) {
+	t.mu.Lock()
+	if t.xmlPending {
+		// メーター用に要求済みでも、それ以外の要求があれば応答で全キーを描画する
+		if !meter {
+			t.xmlMeter = false
+		}
+		t.mu.Unlock()
+		return
+	}
+	t.xmlPending = true
+	t.xmlMeter = meter
+	t.mu.Unlock()
+	t.write(vmixtcp.EVENT_XML)
+}
+
+// handle one response line. XML body is read from r.
+func (t *tcpSession) handle(r *bufio.Reader, line string) error {
+	if line == "" {
+		return nil
+	}
+	resps := strings.SplitN(line, " ", 3)
+	switch resps[0] {
+	case vmixtcp.EVENT_TALLY:
+		if len(resps) != 3 || resps[1] != vmixtcp.STATUS_OK {
+			return nil
+		}
+		t.mu.Lock()
+		t.tally = resps[2]
+		t.mu.Unlock()
+		t.update(func(st *vmixState) {
+			st.Tally = resps[2]
+		})
+	case eventAuth:
+		if len(resps) >= 2 && resps[1] != vmixtcp.STATUS_OK {
+			return fmt.Errorf("vMix rejected credentials:%s", line)
+		}
+	case vmixtcp.EVENT_FUNCTION:
+		// FUNCTION OK Completed / FUNCTION ER Error message
+		if len(resps) < 2 {
+			return nil
+		}
+		var err error
+		if resps[1] != vmixtcp.STATUS_OK {
+			msg := ""
+			if len(resps) == 3 {
+				msg = resps[2]
+			}
+			err = fmt.Errorf("vMix returned error: %s", msg)
+		}
+		t.mu.Lock()
+		if len(t.pending) > 0 {
+			t.pending[0] <- err
+			t.pending = t.pending[1:]
+		}
+		t.mu.Unlock()
+	case vmixtcp.EVENT_ACTS:
+		// 何かが変化したのでXMLを取り直す
+		if len(resps) == 3 && resps[1] == vmixtcp.STATUS_OK {
+			t.requestXML()
+		}
+	case vmixtcp.EVENT_XML:
+		if len(resps) != 2 {
+			return nil
+		}
+		length, err := strconv.Atoi(resps[1])
+		if err != nil {
+			return fmt.Errorf("Unknown XML length:%s", line)
+		}
+		b := make([]byte, length)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return err
+		}
+		st, err := parseState(b)
+
+		t.mu.Lock()
+		t.xmlPending = false
+		redraw := !t.xmlMeter
+		if err == nil {
+			st.Tally = t.tally
+			t.state = st
+		}
+		t.mu.Unlock()
+
+		if err != nil {
+			return err
+		}
+		t.onUpdate(st, redraw, nil)
+	}
+	return nil
+}
+
+// update apply fn to a copy of the current state and publish it.
+// Does nothing until the first XML arrived.
+func (t *tcpSession) update(fn func(st *vmixState)) {
+	t.mu.Lock()
+	if t.state == nil {
+		t.mu.Unlock()
+		return
+	}
+	st := *t.state
+	fn(&st)
+	t.state = &st
+	t.mu.Unlock()
+	t.onUpdate(&st, true, nil)
+}
+
+// Function send FUNCTION and wait for vMix's reply.
+// Waits for the session to connect until ctx is done, but fails at once while it is offline or backing off.
+func (t *tcpSession) Function(ctx context.Context, name string, params map[string]string) error {
+	cmd := vmixtcp.EVENT_FUNCTION + " " + name
+	if len(params) != 0 {
+		q := url.Values{}
+		for k, v := range params {
+			q.Set(k, v)
+		}
+		cmd += " " + q.Encode()
+	}
+
+	t.mu.Lock()
+	for t.conn == nil {
+		// 再接続待ちの間はすぐに失敗させる。ハンドラは順番に実行されるので待つと他のキーも止まる
+		if t.connState == connOffline || t.connState == connBackoff {
+			state := t.connState
+			t.mu.Unlock()
+			return fmt.Errorf("vMix TCP API is not connected (%s)", state)
+		}
+		connected := t.connected
+		t.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("vMix TCP API is not connected: %w", ctx.Err())
+		case <-connected:
+		}
+		t.mu.Lock()
+	}
+	reply := make(chan error, 1)
+	t.pending = append(t.pending, reply)
+	if _, err := io.WriteString(t.conn, cmd+vmixtcp.Terminate); err != nil {
+		t.pending = t.pending[:len(t.pending)-1]
+		t.mu.Unlock()
+		return err
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("No reply for %s: %w", name, ctx.Err())
+	case err := <-reply:
+		return err
+	}
+}
@@ -0,0 +1,248 @@
+package stdvmix
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestSession session that is not running. Updates are ignored.
+func newTestSession() *tcpSession {
+	return newTCPSession(endpoint{Host: "127.0.0.1", TCPPort: vmixTCPPort},
+		func(st *vmixState, redraw bool, err error) {},
+		func(state connState, failures int, err error) {},
+	)
+}
+
+func TestFunctionFailsFastWhileOffline(t *testing.T) {
+	for _, state := range []connState{connOffline, connBackoff} {
+		session := newTestSession()
+		session.setState(state, 1, nil)
+
+		start := time.Now()
+		err := session.Function(context.Background(), "Cut", nil)
+		if err == nil {
+			t.Errorf("%s: Function succeeded without connection", state)
+		}
+		if d := time.Since(start); d > time.Millisecond*100 {
+			t.Errorf("%s: Function waited %v", state, d)
+		}
+	}
+}
+
+const testXML = `<vmix><version>26.0.0.45</version><inputs><input key="k1" number="1" type="Colour" title="Black"/></inputs><preview>1</preview><active>1</active></vmix>`
+
+func TestHandleXMLFraming(t *testing.T) {
+	var got *vmixState
+	session := newTCPSession(endpoint{Host: "127.0.0.1", TCPPort: vmixTCPPort},
+		func(st *vmixState, redraw bool, err error) { got = st },
+		func(state connState, failures int, err error) {},
+	)
+
+	// XMLの後に続く行は本文に含めない
+	r := bufio.NewReader(strings.NewReader(testXML + "TALLY OK 1\r\n"))
+	if err := session.handle(r, "TALLY OK 2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.handle(r, "XML "+strconv.Itoa(len(testXML))); err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.Version != "26.0.0.45" || len(got.Inputs) != 1 {
+		t.Fatalf("state = %+v", got)
+	}
+	if got.Tally != "2" {
+		t.Errorf("Tally = %q, want the TALLY received before XML", got.Tally)
+	}
+	line, err := r.ReadString('\n')
+	if err != nil || line != "TALLY OK 1\r\n" {
+		t.Errorf("next line = %q, %v", line, err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		body string
+		line string
+	}{
+		{"invalid length", testXML, "XML abc"},
+		{"short body", testXML[:10], "XML " + strconv.Itoa(len(testXML))},
+		{"invalid XML", "<vmix>", "XML 6"},
+	} {
+		r := bufio.NewReader(strings.NewReader(tc.body))
+		if err := session.handle(r, tc.line); err == nil {
+			t.Errorf("%s: no error", tc.name)
+		}
+	}
+}
+
+func TestHandleFunctionReplies(t *testing.T) {
+	session := newTestSession()
+	first, second := make(chan error, 1), make(chan error, 1)
+	session.pending = []chan error{first, second}
+
+	if err := session.handle(nil, "FUNCTION OK Completed"); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.handle(nil, "FUNCTION ER Input not found"); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-first; err != nil {
+		t.Errorf("first = %v, want OK", err)
+	}
+	if err := <-second; err == nil || !strings.Contains(err.Error(), "Input not found") {
+		t.Errorf("second = %v, want the ER message", err)
+	}
+	if len(session.pending) != 0 {
+		t.Errorf("%d replies left", len(session.pending))
+	}
+
+	// 待っている呼び出しが無い応答は無視する
+	if err := session.handle(nil, "FUNCTION OK Completed"); err != nil {
+		t.Error(err)
+	}
+}
+
+// fakeVmix vMix TCP API that answers XML and hands FUNCTION lines to the test
+type fakeVmix struct {
+	listener  net.Listener
+	functions chan net.Conn
+}
+
+func newFakeVmix(t *testing.T) *fakeVmix {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	f := &fakeVmix{listener: l, functions: make(chan net.Conn, 1)}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeVmix) serve(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			conn.Close()
+			return
+		}
+		switch cmd := strings.TrimSpace(line); {
+		case cmd == "XML":
+			conn.Write([]byte("XML " + strconv.Itoa(len(testXML)) + "\r\n" + testXML))
+		case strings.HasPrefix(cmd, "FUNCTION "):
+			f.functions <- conn
+			return
+		}
+	}
+}
+
+func (f *fakeVmix) endpoint() endpoint {
+	return endpoint{Host: "127.0.0.1", TCPPort: f.listener.Addr().(*net.TCPAddr).Port}
+}
+
+func TestReconnectFailsPendingFunctions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	vmix := newFakeVmix(t)
+	online := make(chan struct{}, 2)
+	session := newTCPSession(vmix.endpoint(),
+		func(st *vmixState, redraw bool, err error) {},
+		func(state connState, failures int, err error) {
+			if state == connOnline {
+				online <- struct{}{}
+			}
+		},
+	)
+	go session.Run(ctx)
+	waitOnline := func() {
+		t.Helper()
+		select {
+		case <-online:
+		case <-time.After(time.Second * 5):
+			t.Fatal("Session did not connect")
+		}
+	}
+	waitOnline()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- session.Function(ctx, "Cut", nil)
+	}()
+	select {
+	case conn := <-vmix.functions:
+		// 応答せずに切断する
+		conn.Close()
+	case <-time.After(time.Second * 5):
+		t.Fatal("FUNCTION was not sent")
+	}
+	select {
+	case err := <-result:
+		if err == nil || !strings.Contains(err.Error(), "connection closed") {
+			t.Errorf("Function = %v, want connection closed", err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Function did not return after the connection closed")
+	}
+
+	waitOnline()
+	session.mu.Lock()
+	pending := len(session.pending)
+	session.mu.Unlock()
+	if pending != 0 {
+		t.Errorf("%d replies left after reconnect", pending)
+	}
+}
+
+func TestMeterXMLDoesNotRedraw(t *testing.T) {
+	var redraws []bool
+	session := newTCPSession(endpoint{Host: "127.0.0.1", TCPPort: vmixTCPPort},
+		func(st *vmixState, redraw bool, err error) { redraws = append(redraws, redraw) },
+		func(state connState, failures int, err error) {},
+	)
+	reply := func() {
+		t.Helper()
+		r := bufio.NewReader(strings.NewReader(testXML))
+		if err := session.handle(r, "XML "+strconv.Itoa(len(testXML))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 接続していないので送信は失敗するが、要求の種類は記録される
+	session.requestMeterXML()
+	reply()
+	session.requestMeterXML()
+	session.requestXML()
+	reply()
+	if len(redraws) != 2 || redraws[0] || !redraws[1] {
+		t.Errorf("redraws = %v, want [false true]", redraws)
+	}
+}
+
+func TestMeterXMLWaitsForFunctionReplies(t *testing.T) {
+	session := newTestSession()
+	session.pending = []chan error{make(chan error, 1)}
+	session.requestMeterXML()
+	if session.xmlPending {
+		t.Error("meter XML asked while a FUNCTION reply is awaited")
+	}
+
+	// 表示のための要求は待たない
+	session.requestXML()
+	if !session.xmlPending || session.xmlMeter {
+		t.Errorf("xmlPending = %v, xmlMeter = %v", session.xmlPending, session.xmlMeter)
+	}
+}
@@ -0,0 +1,39 @@
+package stdvmix
+
+import (
+	"context"
+	"errors"
+
+	sdcontext "github.com/FlowingSPDG/streamdeck/context"
+)
+
+// setTitleField send the next value of pi to its title field.
+// List position is kept per key because colors can not be read back from vMix.
+func (s *StdVmix) setTitleField(ctx context.Context, pi TitlePI) error {
+	ctxStr := sdcontext.Context(ctx)
+	current := ""
+	st, err := s.store.State(pi.Target())
+	switch {
+	case err == nil:
+		if f, ok := pi.CurrentField(st); ok {
+			current = f.Value
+		}
+	case !errors.Is(err, errNotFetched):
+		return err
+	}
+
+	pos := -1 // 最初の押下でリストの先頭になる
+	if v, ok := s.titles.Load(ctxStr); ok {
+		pos = v.(int)
+	}
+	value, pos, err := pi.NextValue(current, pos)
+	if err != nil {
+		return err
+	}
+	if err := pi.Set(ctx, s.store, value); err != nil {
+		return err
+	}
+	s.titles.Store(ctxStr, pos)
+	s.store.Refresh(pi.Target())
+	return nil
+}
@@ -0,0 +1,367 @@
+package stdvmix
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	vmixtcp "github.com/FlowingSPDG/vmix-go/tcp"
+)
+
+// vmixState vMix XML API snapshot. Only the parts used by actions are decoded.
+type vmixState struct {
+	XMLName  xml.Name      `xml:"vmix"`
+	Version  string        `xml:"version"`
+	Edition  string        `xml:"edition"`
+	Inputs   []vmixInput   `xml:"inputs>input"`
+	Overlays []vmixOverlay `xml:"overlays>overlay"`
+	Preview  int           `xml:"preview"`
+	Active   int           `xml:"active"`
+	Mixes    []vmixMix     `xml:"mix"` // Mix 2-
+
+	Recording   bool          `xml:"recording"`
+	External    bool          `xml:"external"`
+	Streaming   vmixStreaming `xml:"streaming"`
+	MultiCorder bool          `xml:"multiCorder"`
+
+	Audio vmixAudio `xml:"audio"`
+
+	// Tally latest TALLY response. one digit per input number.
+	Tally string `xml:"-"`
+}
+
+// vmixInput single <input> element
+type vmixInput struct {
+	Key    string `xml:"key,attr"`
+	Number int    `xml:"number,attr"`
+	Type   string `xml:"type,attr"`
+	Title  string `xml:"title,attr"`
+	State  string `xml:"state,attr"`
+
+	// audio. Inputs without audio have no attributes and stay muted at 0
+	Volume float64 `xml:"volume,attr"` // 0-100
+	Muted  bool    `xml:"muted,attr"`
+	Solo   bool    `xml:"solo,attr"`
+
+	// MeterF1 MeterF2 left/right level. 0-1 amplitude
+	MeterF1 float64 `xml:"meterF1,attr"`
+	MeterF2 float64 `xml:"meterF2,attr"`
+
+	// AudioBusses buses the input is sent to. e.g. "M,A,C"
+	AudioBusses string `xml:"audiobusses,attr"`
+
+	// Texts Images title(GT) fields
+	Texts  []vmixField `xml:"text"`
+	Images []vmixField `xml:"image"`
+
+	// Replay replay state. Replay input only
+	Replay *vmixReplay `xml:"replay"`
+
+	// Layers inputs shown inside this input (multiview/layers)
+	Layers []vmixLayer `xml:"overlay"`
+}
+
+// vmixLayer <overlay> element of an input. Key of the input used as a layer
+type vmixLayer struct {
+	Index int    `xml:"index,attr"`
+	Key   string `xml:"key,attr"`
+}
+
+// vmixReplay <replay> element of the replay input
+type vmixReplay struct {
+	Live      bool    `xml:"live,attr"`
+	Recording bool    `xml:"recording,attr"`
+	Events    int     `xml:"events,attr"`  // selected event list 1-20
+	CameraA   int     `xml:"cameraA,attr"` // camera 1-8 of channel A
+	Speed     float64 `xml:"speed,attr"`   // 0-1 of the current channel
+}
+
+// vmixField <text> or <image> field of a title input
+type vmixField struct {
+	Index int    `xml:"index,attr"`
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// findField find title field by name(e.g. "Headline.Text") or index
+func (i vmixInput) findField(name string, index int, byIndex bool) (vmixField, bool) {
+	for _, fields := range [][]vmixField{i.Texts, i.Images} {
+		for _, f := range fields {
+			if (byIndex && f.Index == index) || (!byIndex && f.Name == name) {
+				return f, true
+			}
+		}
+	}
+	return vmixField{}, false
+}
+
+// label key title of the input. "number\ntitle"
+func (i vmixInput) label() string {
+	return strconv.Itoa(i.Number) + "\n" + i.Title
+}
+
+// onBus the input is routed to bus("M" or "A"-"G")
+func (i vmixInput) onBus(bus string) bool {
+	for _, b := range strings.Split(i.AudioBusses, ",") {
+		if b == bus {
+			return true
+		}
+	}
+	return false
+}
+
+// vmixOverlay single <overlay> element. Input is empty(0) when nothing is on the channel.
+type vmixOverlay struct {
+	Number  int  `xml:"number,attr"`
+	Preview bool `xml:"preview,attr"` // the channel is shown in preview, not output
+	Input   int  `xml:",chardata"`
+}
+
+// vmixMix <mix> element of Mix 2 and later
+type vmixMix struct {
+	Number  int `xml:"number,attr"`
+	Preview int `xml:"preview"`
+	Active  int `xml:"active"`
+}
+
+// vmixAudio <audio> element. Master and Bus A-G
+type vmixAudio struct {
+	Master vmixBus `xml:"master"`
+	BusA   vmixBus `xml:"busA"`
+	BusB   vmixBus `xml:"busB"`
+	BusC   vmixBus `xml:"busC"`
+	BusD   vmixBus `xml:"busD"`
+	BusE   vmixBus `xml:"busE"`
+	BusF   vmixBus `xml:"busF"`
+	BusG   vmixBus `xml:"busG"`
+}
+
+// vmixBus master or bus element
+type vmixBus struct {
+	XMLName xml.Name
+	Volume  float64 `xml:"volume,attr"` // 0-100
+	Muted   bool    `xml:"muted,attr"`
+	MeterF1 float64 `xml:"meterF1,attr"`
+	MeterF2 float64 `xml:"meterF2,attr"`
+}
+
+// vmixStreaming <streaming> element. Live is true while any channel is streaming.
+type vmixStreaming struct {
+	Live     bool `xml:",chardata"`
+	Channel1 bool `xml:"channel1,attr"`
+	Channel2 bool `xml:"channel2,attr"`
+	Channel3 bool `xml:"channel3,attr"`
+}
+
+// channel streaming state of channel "0"-"2". "" for any channel.
+func (s vmixStreaming) channel(ch string) (bool, error) {
+	switch ch {
+	case "":
+		return s.Live, nil
+	case "0":
+		return s.Channel1, nil
+	case "1":
+		return s.Channel2, nil
+	case "2":
+		return s.Channel3, nil
+	}
+	return false, fmt.Errorf("Invalid streaming channel:%s", ch)
+}
+
+// parseState decode vMix XML
+func parseState(b []byte) (*vmixState, error) {
+	st := &vmixState{}
+	if err := xml.Unmarshal(b, st); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal XML... %w", err)
+	}
+	return st, nil
+}
+
+// inputList input list for Property Inspector
+func (st *vmixState) inputList() []input {
+	ret := make([]input, 0, len(st.Inputs))
+	for _, i := range st.Inputs {
+		ret = append(ret, input{
+			Name:   i.Title,
+			Key:    i.Key,
+			Number: i.Number,
+		})
+	}
+	return ret
+}
+
+// mixList mix list for Property Inspector. Main and the mixes enabled in vMix
+func (st *vmixState) mixList() []mix {
+	ret := []mix{{Name: "Main", Value: ""}}
+	for _, m := range st.Mixes {
+		ret = append(ret, mix{Name: fmt.Sprintf("Mix %d", m.Number), Value: strconv.Itoa(m.Number - 1)})
+	}
+	return ret
+}
+
+// findInput find input by key, number or title, same as the Input parameter of vMix functions
+func (st *vmixState) findInput(ref string) (vmixInput, bool) {
+	for _, i := range st.Inputs {
+		if i.Key == ref {
+			return i, true
+		}
+	}
+	if n, err := strconv.Atoi(ref); err == nil {
+		for _, i := range st.Inputs {
+			if i.Number == n {
+				return i, true
+			}
+		}
+	}
+	for _, i := range st.Inputs {
+		if i.Title == ref {
+			return i, true
+		}
+	}
+	return vmixInput{}, false
+}
+
+// findReplay replay state of the first replay input
+func (st *vmixState) findReplay() (vmixReplay, bool) {
+	for _, i := range st.Inputs {
+		if i.Replay != nil {
+			return *i.Replay, true
+		}
+	}
+	return vmixReplay{}, false
+}
+
+// findOverlay find overlay channel by number
+func (st *vmixState) findOverlay(number int) (vmixOverlay, bool) {
+	for _, o := range st.Overlays {
+		if o.Number == number {
+			return o, true
+		}
+	}
+	return vmixOverlay{}, false
+}
+
+// findBus find master("M") or bus "A"-"G". Buses not enabled in vMix are not found.
+func (st *vmixState) findBus(name string) (vmixBus, bool) {
+	var b vmixBus
+	switch name {
+	case busMaster:
+		b = st.Audio.Master
+	case "A":
+		b = st.Audio.BusA
+	case "B":
+		b = st.Audio.BusB
+	case "C":
+		b = st.Audio.BusC
+	case "D":
+		b = st.Audio.BusD
+	case "E":
+		b = st.Audio.BusE
+	case "F":
+		b = st.Audio.BusF
+	case "G":
+		b = st.Audio.BusG
+	}
+	return b, b.XMLName.Local != ""
+}
+
+// tallyOf tally status of input number, same as the tally lights of vMix.
+// Program if the input is live in any way: active, on an overlay channel, a layer of a live input or active in a mix.
+// Preview likewise.
+func (st *vmixState) tallyOf(number int) vmixtcp.TallyStatus {
+	program, preview := st.tallyInputs()
+	switch {
+	case program[number]:
+		return vmixtcp.Program
+	case preview[number]:
+		return vmixtcp.Preview
+	}
+	return vmixtcp.Off
+}
+
+// tallyInputs input numbers in program and preview, including layers of them.
+// The main mix comes from TALLY once vMix has sent it, since TALLY arrives on every cut and XML may be older.
+// Overlays, layers and the other mixes are not in TALLY and come from XML.
+func (st *vmixState) tallyInputs() (program, preview map[int]bool) {
+	programs, previews := st.mainTally()
+	for _, o := range st.Overlays {
+		if o.Preview {
+			previews = append(previews, o.Input)
+		} else {
+			programs = append(programs, o.Input)
+		}
+	}
+	for _, m := range st.Mixes {
+		programs = append(programs, m.Active)
+		previews = append(previews, m.Preview)
+	}
+
+	program, preview = map[int]bool{}, map[int]bool{}
+	st.markLayers(program, programs)
+	st.markLayers(preview, previews)
+	return program, preview
+}
+
+// mainTally input numbers in program and preview of the main mix. From TALLY if received, otherwise from XML
+func (st *vmixState) mainTally() (programs, previews []int) {
+	if st.Tally == "" {
+		return []int{st.Active}, []int{st.Preview}
+	}
+	for i, c := range st.Tally {
+		switch c {
+		case '1':
+			programs = append(programs, i+1)
+		case '2':
+			previews = append(previews, i+1)
+		}
+	}
+	return programs, previews
+}
+
+// markLayers add numbers and their layers to set. Inputs already in set are skipped so nested layers can not loop.
+func (st *vmixState) markLayers(set map[int]bool, numbers []int) {
+	for _, n := range numbers {
+		if n == 0 || set[n] {
+			continue
+		}
+		set[n] = true
+		input, ok := st.findInput(strconv.Itoa(n))
+		if !ok {
+			continue
+		}
+		for _, l := range input.Layers {
+			if layer, ok := st.findInput(l.Key); ok {
+				st.markLayers(set, []int{layer.Number})
+			}
+		}
+	}
+}
+
+// tallyOfMix tally status of input number in mix.
+// mix is the Mix parameter of functions: "" or "0" for the main mix, "1" for Mix 2 (<mix number="2">) ...
+func (st *vmixState) tallyOfMix(mix string, number int) (vmixtcp.TallyStatus, error) {
+	if mix == "" || mix == "0" {
+		return st.tallyOf(number), nil
+	}
+	n, err := strconv.Atoi(mix)
+	if err != nil {
+		return vmixtcp.Off, fmt.Errorf("Invalid mix:%s", mix)
+	}
+	for _, m := range st.Mixes {
+		if m.Number != n+1 {
+			continue
+		}
+		program, preview := map[int]bool{}, map[int]bool{}
+		st.markLayers(program, []int{m.Active})
+		st.markLayers(preview, []int{m.Preview})
+		switch {
+		case program[number]:
+			return vmixtcp.Program, nil
+		case preview[number]:
+			return vmixtcp.Preview, nil
+		}
+		return vmixtcp.Off, nil
+	}
+	return vmixtcp.Off, fmt.Errorf("No mix found:%s", mix)
+}
@@ -0,0 +1,167 @@
+package stdvmix
+
+import (
+	"testing"
+
+	vmixtcp "github.com/FlowingSPDG/vmix-go/tcp"
+)
+
+// tallyXML 1: active, 2: preview, 3: layer of 1, 4: layer of 3 and 1 (cycle),
+// 5: overlay on output, 6: overlay on preview, 7: Mix 2 active, 8: Mix 2 preview, 9: nothing
+const tallyXML = `<vmix>
+<version>26.0.0.45</version>
+<inputs>
+<input key="k1" number="1" title="Camera 1"><overlay index="0" key="k3"/></input>
+<input key="k2" number="2" title="Camera 2"/>
+<input key="k3" number="3" title="Multiview"><overlay index="0" key="k4"/></input>
+<input key="k4" number="4" title="Logo"><overlay index="0" key="k3"/><overlay index="1" key="k1"/></input>
+<input key="k5" number="5" title="Lower third"/>
+<input key="k6" number="6" title="Next up"/>
+<input key="k7" number="7" title="Mix camera"/>
+<input key="k8" number="8" title="Mix preview"/>
+<input key="k9" number="9" title="Unused"/>
+</inputs>
+<overlays>
+<overlay number="1">5</overlay>
+<overlay number="2" preview="True">6</overlay>
+<overlay number="3"/>
+</overlays>
+<preview>2</preview>
+<active>1</active>
+<mix number="2"><preview>8</preview><active>7</active></mix>
+</vmix>`
+
+func parseTestState(t *testing.T) *vmixState {
+	t.Helper()
+	st, err := parseState([]byte(tallyXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestParseState(t *testing.T) {
+	st := parseTestState(t)
+	if st.Version != "26.0.0.45" || st.Active != 1 || st.Preview != 2 {
+		t.Errorf("state = %+v", st)
+	}
+	if len(st.Inputs) != 9 || st.Inputs[3].Title != "Logo" || len(st.Inputs[3].Layers) != 2 {
+		t.Errorf("inputs = %+v", st.Inputs)
+	}
+	if o, ok := st.findOverlay(2); !ok || !o.Preview || o.Input != 6 {
+		t.Errorf("overlay 2 = %+v, %v", o, ok)
+	}
+	if o, ok := st.findOverlay(3); !ok || o.Input != 0 {
+		t.Errorf("empty overlay 3 = %+v, %v", o, ok)
+	}
+	if len(st.Mixes) != 1 || st.Mixes[0].Number != 2 || st.Mixes[0].Active != 7 {
+		t.Errorf("mixes = %+v", st.Mixes)
+	}
+
+	if _, err := parseState([]byte("<vmix>")); err == nil {
+		t.Error("broken XML parsed")
+	}
+}
+
+func TestTallyOf(t *testing.T) {
+	st := parseTestState(t)
+	for number, want := range map[int]vmixtcp.TallyStatus{
+		1: vmixtcp.Program,
+		2: vmixtcp.Preview,
+		3: vmixtcp.Program,
+		4: vmixtcp.Program,
+		5: vmixtcp.Program,
+		6: vmixtcp.Preview,
+		7: vmixtcp.Program,
+		8: vmixtcp.Preview,
+		9: vmixtcp.Off,
+		0: vmixtcp.Off,
+	} {
+		if got := st.tallyOf(number); got != want {
+			t.Errorf("tallyOf(%d) = %v, want %v", number, got, want)
+		}
+	}
+
+	// カットした直後。TALLYの方が新しいのでメインのミックスはTALLYに従う
+	// オーバーレイとMix 2はTALLYに無いのでXMLのまま
+	st.Tally = "200000001"
+	for number, want := range map[int]vmixtcp.TallyStatus{
+		1: vmixtcp.Preview,
+		2: vmixtcp.Off,
+		3: vmixtcp.Preview,
+		4: vmixtcp.Preview,
+		5: vmixtcp.Program,
+		6: vmixtcp.Preview,
+		7: vmixtcp.Program,
+		8: vmixtcp.Preview,
+		9: vmixtcp.Program,
+	} {
+		if got := st.tallyOf(number); got != want {
+			t.Errorf("tallyOf(%d) with TALLY %s = %v, want %v", number, st.Tally, got, want)
+		}
+	}
+
+	// XMLではプレビューの入力がTALLYでプログラムに出ている
+	st.Tally = "010000000"
+	if got := st.tallyOf(2); got != vmixtcp.Program {
+		t.Errorf("tallyOf(2) with TALLY program = %v", got)
+	}
+	if got := st.tallyOf(1); got != vmixtcp.Off {
+		t.Errorf("tallyOf(1) with TALLY off = %v", got)
+	}
+}
+
+func TestTallyOfMix(t *testing.T) {
+	st := parseTestState(t)
+	for _, tc := range []struct {
+		mix    string
+		number int
+		want   vmixtcp.TallyStatus
+	}{
+		{"", 1, vmixtcp.Program},
+		{"0", 7, vmixtcp.Program},
+		{"1", 7, vmixtcp.Program},
+		{"1", 8, vmixtcp.Preview},
+		{"1", 1, vmixtcp.Off},
+		{"1", 2, vmixtcp.Off},
+	} {
+		got, err := st.tallyOfMix(tc.mix, tc.number)
+		if err != nil {
+			t.Errorf("tallyOfMix(%q, %d): %v", tc.mix, tc.number, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("tallyOfMix(%q, %d) = %v, want %v", tc.mix, tc.number, got, tc.want)
+		}
+	}
+
+	for _, mix := range []string{"2", "x"} {
+		if _, err := st.tallyOfMix(mix, 1); err == nil {
+			t.Errorf("tallyOfMix(%q): no error", mix)
+		}
+	}
+}
+
+func TestOverlayUpdateTally(t *testing.T) {
+	st := parseTestState(t)
+	for _, tc := range []struct {
+		input   string
+		channel int
+		want    tallyKind
+	}{
+		{"5", 1, tallyOnOverlay},
+		{"6", 2, tallyOnPreview}, // プレビューだけのチャンネルはオンエアではない
+		{"6", 1, tallyOnPreview},
+		{"1", 3, tallyOnProgram},
+		{"9", 3, tallyOff},
+	} {
+		got, err := OverlayPI{Input: tc.input, Channel: tc.channel}.UpdateTally(st)
+		if err != nil {
+			t.Errorf("UpdateTally(%s, %d): %v", tc.input, tc.channel, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("UpdateTally(%s, %d) = %v, want %v", tc.input, tc.channel, got, tc.want)
+		}
+	}
+}
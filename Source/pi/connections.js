@@ -0,0 +1,128 @@
+// Connection profiles shared by every action.
+// Profiles are stored in plugin global settings and each action keeps only the profile id.
+// Other pages (e.g. tally.js) use globalSettings and setGlobalSettings too.
+
+var connections = [];
+var globalSettings = {};
+
+document.addEventListener('websocketCreate', function () {
+    websocket.addEventListener('open', function () {
+        websocket.send(JSON.stringify({
+            'event': 'getGlobalSettings',
+            'context': uuid
+        }));
+    });
+    websocket.addEventListener('message', function (evt) {
+        var jsonObj = JSON.parse(evt.data);
+        if (jsonObj.event === 'didReceiveGlobalSettings') {
+            globalSettings = jsonObj.payload.settings || {};
+            connections = globalSettings.connections || [];
+            loadConnections();
+        }
+        else if (jsonObj.event === 'didReceiveSettings') {
+            actionInfo.payload.settings = jsonObj.payload.settings;
+            loadConnections();
+        }
+    });
+});
+
+// loadConnections fill the connection list and keep the saved selection
+function loadConnections() {
+    var elem = document.getElementById('connection');
+    var selected = (actionInfo.payload.settings || {}).connection || elem.value;
+    elem.options.length = 0;
+
+    var opt = document.createElement('option');
+    opt.value = '';
+    opt.text = '(Host/Port below)';
+    elem.appendChild(opt);
+
+    connections.forEach(function (c) {
+        var opt = document.createElement('option');
+        opt.value = c.id;
+        opt.text = c.name || c.host;
+        elem.appendChild(opt);
+    });
+    elem.value = selected;
+    if (elem.value !== selected) {
+        elem.value = '';
+    }
+    showConnection();
+}
+
+// showConnection show Host/Port only when no profile is selected, and load the profile into the editor
+function showConnection() {
+    var id = document.getElementById('connection').value;
+    document.querySelectorAll('.legacyConnection').forEach(function (e) {
+        e.style.display = id ? 'none' : '';
+    });
+
+    var c = connections.find(function (c) { return c.id === id; }) || {};
+    document.getElementById('profile_name').value = c.name || '';
+    document.getElementById('profile_host').value = c.host || '';
+    document.getElementById('profile_tcp_port').value = c.tcp_port || '';
+    document.getElementById('profile_user').value = c.user || '';
+    document.getElementById('profile_password').value = c.password || '';
+}
+
+function onConnectionChange() {
+    actionInfo.payload.settings = actionInfo.payload.settings || {};
+    actionInfo.payload.settings.connection = document.getElementById('connection').value;
+    showConnection();
+    setSettings();
+}
+
+// setGlobalSettings save every global setting. Other settings (e.g. tally colors) are kept as received.
+function setGlobalSettings() {
+    if (websocket && (websocket.readyState === 1)) {
+        globalSettings.connections = connections;
+        websocket.send(JSON.stringify({
+            'event': 'setGlobalSettings',
+            'context': uuid,
+            'payload': globalSettings
+        }));
+    }
+}
+
+// saveConnection update the selected profile, or add a new one when none is selected
+function saveConnection() {
+    var elem = document.getElementById('connection');
+    var id = elem.value || Date.now().toString(36);
+    var old = connections.find(function (c) { return c.id === id; }) || {};
+    var c = {
+        'id': id,
+        'name': document.getElementById('profile_name').value,
+        'host': document.getElementById('profile_host').value,
+        // HTTP port is not edited. The plugin only talks TCP; it is kept to match legacy Host/Port settings
+        'port': old.port || '8088',
+        'tcp_port': document.getElementById('profile_tcp_port').value || '8099',
+        'user': document.getElementById('profile_user').value,
+        'password': document.getElementById('profile_password').value
+    };
+    var idx = connections.findIndex(function (c) { return c.id === id; });
+    if (idx < 0) {
+        connections.push(c);
+    } else {
+        connections[idx] = c;
+    }
+    setGlobalSettings();
+
+    actionInfo.payload.settings = actionInfo.payload.settings || {};
+    actionInfo.payload.settings.connection = id;
+    loadConnections();
+    setSettings();
+}
+
+// deleteConnection remove the selected profile. Actions using it fall back to Host/Port.
+function deleteConnection() {
+    var id = document.getElementById('connection').value;
+    if (!id) {
+        return;
+    }
+    connections = connections.filter(function (c) { return c.id !== id; });
+    setGlobalSettings();
+
+    actionInfo.payload.settings.connection = '';
+    loadConnections();
+    setSettings();
+}
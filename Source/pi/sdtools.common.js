@@ -59,6 +59,10 @@ function websocketOnMessage(evt) {
         var payload = jsonObj.payload;
         loadConfiguration(payload.settings);
     }
+    else if (jsonObj.event === 'sendToPropertyInspector') {
+        // live data from the plugin (e.g. vMix input list). Not saved in settings
+        loadConfiguration(jsonObj.payload);
+    }
     var event = new Event('onmessage',jsonObj.payload);
     document.dispatchEvent(event);
 }
@@ -85,16 +89,23 @@ function loadConfiguration(payload) {
                 var valueField = elem.getAttribute("sdValueField");
 
                 var items = payload[key];
+                // without the selection in the payload (e.g. vMix offline), keep the current one
+                var value = valueField in payload ? payload[valueField] : elem.value;
                 elem.options.length = 0;
 
                 for (var idx = 0; idx < items.length; idx++) {
                     var opt = document.createElement('option');
                     opt.value = items[idx][valueProperty];
                     // for vMix input number
-                    opt.text = `${items[idx].number} : ${items[idx][textProperty]}`;
+                    if (items[idx].number !== undefined) {
+                        opt.text = `${items[idx].number} : ${items[idx][textProperty]}`;
+                    }
+                    else {
+                        opt.text = items[idx][textProperty];
+                    }
                     elem.appendChild(opt);
                 }
-                elem.value = payload[valueField];
+                selectListValue(elem, value);
             }
             else if (elem.classList.contains("sdHTML")) { // HTML element
                 elem.innerHTML = payload[key];
@@ -108,6 +119,38 @@ function loadConfiguration(payload) {
             console.log("loadConfiguration failed for key: " + key + " - " + err);
         }
     }
+    seedLists(payload);
+}
+
+// seedLists select the saved value of dynamic dropdowns whose list is not in payload.
+// The list is pushed by the plugin later (or never while vMix is offline),
+// and setSettings on an empty dropdown would save "" over the saved value.
+function seedLists(payload) {
+    document.querySelectorAll('.sdList').forEach(function (elem) {
+        var valueField = elem.getAttribute("sdValueField");
+        if (elem.id in payload || !(valueField in payload)) {
+            return;
+        }
+        selectListValue(elem, payload[valueField]);
+    });
+}
+
+// selectListValue select value in a dynamic dropdown, adding it as an option if the list does not have it
+function selectListValue(elem, value) {
+    if (value === undefined || value === null) {
+        return;
+    }
+    value = String(value);
+    var found = Array.prototype.some.call(elem.options, function (opt) {
+        return opt.value === value;
+    });
+    if (!found) {
+        var opt = document.createElement('option');
+        opt.value = value;
+        opt.text = value;
+        elem.appendChild(opt);
+    }
+    elem.value = value;
 }
 
 function setSettings() {
@@ -0,0 +1,36 @@
+// Tally colors of preview/program/overlay keys.
+// Empty fields use the default colors of every key, which are stored in plugin global settings.
+// Requires connections.js for globalSettings.
+
+var tallyFields = ['tally_preview', 'tally_program', 'tally_overlay', 'tally_inactive', 'tally_style'];
+
+document.addEventListener('websocketCreate', function () {
+    websocket.addEventListener('message', function (evt) {
+        var jsonObj = JSON.parse(evt.data);
+        if (jsonObj.event === 'didReceiveGlobalSettings') {
+            showTallyDefaults();
+        }
+    });
+});
+
+// showTallyDefaults show the default colors as placeholders
+function showTallyDefaults() {
+    var tally = globalSettings.tally || {};
+    tallyFields.forEach(function (key) {
+        var elem = document.getElementById(key);
+        if (elem.tagName === 'INPUT') {
+            elem.placeholder = tally[key] || elem.dataset.default;
+        }
+    });
+}
+
+// saveTallyDefaults save the colors of this key as the default of every key
+function saveTallyDefaults() {
+    var tally = {};
+    tallyFields.forEach(function (key) {
+        tally[key] = document.getElementById(key).value;
+    });
+    globalSettings.tally = tally;
+    setGlobalSettings();
+    showTallyDefaults();
+}
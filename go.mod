@@ -5,9 +5,7 @@ go 1.19
 require (
 	github.com/FlowingSPDG/streamdeck v0.0.0-20221216130808-df1199768e06
 	github.com/FlowingSPDG/vmix-go v0.2.3
+	github.com/gorilla/websocket v1.5.0
 )
 
-require (
-	github.com/gorilla/websocket v1.5.0 // indirect
-	golang.org/x/sync v0.1.0 // indirect
-)
+require golang.org/x/sync v0.1.0 // indirect